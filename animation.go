@@ -0,0 +1,178 @@
+package buttery
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+
+	"github.com/andybons/gogif"
+)
+
+// Animation models a truecolor frame sequence, independent of file format.
+//
+// Unlike GIF, frames hold full canvases with 8-bit alpha,
+// free of palette quantization.
+type Animation struct {
+	// Timeline holds the full canvas frames.
+	Timeline Timeline
+
+	// LoopCount follows gif.GIF.LoopCount.
+	//
+	// -1 indicates one play.
+	// 0 indicates infinite, endless plays.
+	// N indicates 1+N iterations.
+	LoopCount int
+}
+
+// NewAnimation renders a GIF as an Animation, honoring disposal methods.
+func NewAnimation(sourceGif *gif.GIF) (*Animation, error) {
+	if err := validateSource(sourceGif); err != nil {
+		return nil, err
+	}
+
+	images := Render(sourceGif)
+	timeline := make(Timeline, len(images))
+
+	for i, img := range images {
+		timeline[i] = Frame{Image: img, Disposal: gif.DisposalNone}
+
+		if i < len(sourceGif.Delay) {
+			timeline[i].Delay = sourceGif.Delay[i]
+		}
+	}
+
+	return &Animation{Timeline: timeline, LoopCount: sourceGif.LoopCount}, nil
+}
+
+// decodeMinPixels denotes the composited pixel total allowed any input, see decodeBudget.
+const decodeMinPixels = 1 << 26
+
+// decodePixelsPerByte bounds the composited pixels per input byte beyond decodeMinPixels, see decodeBudget.
+const decodePixelsPerByte = 1 << 10
+
+// decodeBudget bounds the total pixels that decoders may composite from an input of a given size in bytes,
+// guarding against small inputs declaring large canvases over many frames.
+func decodeBudget(inputSize int64) int64 {
+	return max(decodeMinPixels, inputSize*decodePixelsPerByte)
+}
+
+// Bounds queries the canvas, the union of frame bounds from the origin.
+func (o *Animation) Bounds() image.Rectangle {
+	var bounds image.Rectangle
+
+	// Union skips empty rectangles, so anchor each frame at the origin.
+	for _, frame := range o.Timeline {
+		frameBounds := frame.Image.Bounds()
+		bounds = bounds.Union(image.Rect(0, 0, frameBounds.Max.X, frameBounds.Max.Y))
+	}
+
+	return bounds
+}
+
+// Plays converts LoopCount to a total play count, zero indicating infinite plays,
+// as used by APNG and WebP.
+func (o *Animation) Plays() int {
+	switch {
+	case o.LoopCount < 0:
+		return 1
+	case o.LoopCount == 0:
+		return 0
+	default:
+		return o.LoopCount + 1
+	}
+}
+
+// loopCountForPlays converts a total play count, zero indicating infinite plays,
+// to a GIF loop count.
+func loopCountForPlays(plays int) int {
+	switch {
+	case plays == 0:
+		return 0
+	case plays == 1:
+		return -1
+	default:
+		return plays - 1
+	}
+}
+
+// GIF quantizes the animation to a GIF,
+// with each frame replacing the whole logical screen.
+func (o *Animation) GIF() (*gif.GIF, error) {
+	if len(o.Timeline) == 0 {
+		return nil, ErrNoFrames
+	}
+
+	screen := o.Bounds()
+	animationGif := gif.GIF{
+		LoopCount: o.LoopCount,
+		Config:    image.Config{Width: screen.Dx(), Height: screen.Dy()},
+		Image:     make([]*image.Paletted, len(o.Timeline)),
+		Delay:     o.Timeline.Delays(),
+		Disposal:  make([]byte, len(o.Timeline)),
+	}
+
+	quantizer := gogif.MedianCutQuantizer{NumColor: 256}
+
+	for i, frame := range o.Timeline {
+		if frame.Image == nil {
+			return nil, ErrInvalidFrame
+		}
+
+		canvas := image.NewRGBA(screen)
+		draw.Draw(canvas, frame.Image.Bounds(), frame.Image, frame.Image.Bounds().Min, draw.Src)
+		animationGif.Image[i] = quantize(&quantizer, canvas)
+
+		// Each frame stands alone, replacing the whole screen.
+		animationGif.Disposal[i] = gif.DisposalBackground
+	}
+
+	return &animationGif, nil
+}
+
+// EditAnimation applies the pipeline to a truecolor animation.
+//
+// The source animation is left unmodified.
+func (o Pipeline) EditAnimation(sourceAnimation *Animation) (*Animation, error) {
+	return o.EditAnimationContext(context.Background(), sourceAnimation)
+}
+
+// EditAnimationContext applies the pipeline to a truecolor animation,
+// reporting progress and honoring cancellation.
+//
+// The source animation is left unmodified.
+func (o Pipeline) EditAnimationContext(ctx context.Context, sourceAnimation *Animation) (*Animation, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	if len(sourceAnimation.Timeline) == 0 {
+		return nil, ErrNoFrames
+	}
+
+	timeline, err := o.Apply(WithWorkers(ctx, o.Workers), sourceAnimation.Timeline)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Animation{Timeline: timeline, LoopCount: o.LoopCount}, nil
+}
+
+// toNRGBA converts an image to non-premultiplied RGBA, as stored by PNG and WebP.
+func toNRGBA(img image.Image, bounds image.Rectangle) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Rect == bounds {
+		return nrgba
+	}
+
+	nrgba := image.NewNRGBA(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			nrgba.SetNRGBA(x, y, c)
+		}
+	}
+
+	return nrgba
+}
@@ -0,0 +1,75 @@
+package buttery_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+func TestAnimationOffsetFrames(t *testing.T) {
+	var images []image.Image
+
+	for _, r := range []image.Rectangle{image.Rect(2, 2, 6, 4), image.Rect(4, 2, 8, 6)} {
+		img := image.NewNRGBA(image.Rect(0, 0, 8, 6))
+		draw.Draw(img, r, image.NewUniform(color.White), image.Point{}, draw.Src)
+		images = append(images, img.SubImage(r))
+	}
+
+	timeline, err := buttery.NewTimelineFromImages(images, []int{4, 4})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sourceAnimation := &buttery.Animation{Timeline: timeline}
+	expected := image.Rect(0, 0, 8, 6)
+
+	if bounds := sourceAnimation.Bounds(); bounds != expected {
+		t.Fatalf("expected bounds %v, got %v", expected, bounds)
+	}
+
+	animationGif, err := sourceAnimation.GIF()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := gif.EncodeAll(&buf, animationGif); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, codec := range map[string]struct {
+		encode func(w io.Writer, animation *buttery.Animation) error
+		decode func(r io.Reader) (*buttery.Animation, error)
+	}{
+		"APNG": {encode: buttery.EncodeAPNG, decode: buttery.DecodeAPNG},
+		"WebP": {encode: buttery.EncodeWebP, decode: buttery.DecodeWebP},
+		"Y4M":  {encode: buttery.EncodeY4M, decode: buttery.DecodeY4M},
+	} {
+		buf.Reset()
+
+		if err := codec.encode(&buf, sourceAnimation); err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+
+		animation, err := codec.decode(&buf)
+
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+
+		for i, frame := range animation.Timeline {
+			if bounds := frame.Image.Bounds(); bounds != expected {
+				t.Errorf("expected %v frame %d bounds %v, got %v", name, i, expected, bounds)
+			}
+		}
+	}
+}
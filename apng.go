@@ -0,0 +1,574 @@
+package buttery
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+)
+
+// pngSignature opens every PNG stream.
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// apngMaxPixels bounds the canvas area, guarding against corrupt headers.
+const apngMaxPixels = 1 << 28
+
+// APNG frame disposal operations.
+const (
+	apngDisposeNone       = 0
+	apngDisposeBackground = 1
+	apngDisposePrevious   = 2
+)
+
+// APNG frame blend operations.
+const (
+	apngBlendSource = 0
+	apngBlendOver   = 1
+)
+
+// pngChunk models a PNG chunk.
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+// readPNGChunk reads a PNG chunk, verifying its checksum.
+func readPNGChunk(r io.Reader) (pngChunk, error) {
+	var header [8]byte
+
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return pngChunk{}, err
+	}
+
+	length := binary.BigEndian.Uint32(header[:4])
+
+	if length > 1<<31-1 {
+		return pngChunk{}, fmt.Errorf("%w: chunk length %d", ErrInvalidAPNG, length)
+	}
+
+	// Buffers grow with the data actually read, such that corrupt lengths fail without large allocations.
+	var buf bytes.Buffer
+
+	if _, err := io.CopyN(&buf, r, int64(length)+4); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return pngChunk{}, err
+	}
+
+	data := buf.Bytes()
+
+	checksum := crc32.NewIEEE()
+	checksum.Write(header[4:])
+	checksum.Write(data[:length])
+
+	if checksum.Sum32() != binary.BigEndian.Uint32(data[length:]) {
+		return pngChunk{}, fmt.Errorf("%w: %v chunk checksum mismatch", ErrInvalidAPNG, string(header[4:]))
+	}
+
+	return pngChunk{kind: string(header[4:]), data: data[:length]}, nil
+}
+
+// writePNGChunk writes a PNG chunk with its checksum.
+func writePNGChunk(w io.Writer, kind string, data []byte) error {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], kind)
+	checksum := crc32.NewIEEE()
+	checksum.Write(header[4:])
+	checksum.Write(data)
+	var footer [4]byte
+	binary.BigEndian.PutUint32(footer[:], checksum.Sum32())
+
+	for _, b := range [][]byte{header[:], data, footer[:]} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apngFrame models an APNG frame control chunk and its image data.
+type apngFrame struct {
+	width, height    uint32
+	x, y             uint32
+	delayNum         uint16
+	delayDen         uint16
+	dispose, blend   byte
+	data             []byte
+	hasData, started bool
+}
+
+// parseFrameControl parses an fcTL chunk.
+func parseFrameControl(data []byte) (*apngFrame, error) {
+	if len(data) != 26 {
+		return nil, fmt.Errorf("%w: fcTL length %d", ErrInvalidAPNG, len(data))
+	}
+
+	return &apngFrame{
+		width:    binary.BigEndian.Uint32(data[4:]),
+		height:   binary.BigEndian.Uint32(data[8:]),
+		x:        binary.BigEndian.Uint32(data[12:]),
+		y:        binary.BigEndian.Uint32(data[16:]),
+		delayNum: binary.BigEndian.Uint16(data[20:]),
+		delayDen: binary.BigEndian.Uint16(data[22:]),
+		dispose:  data[24],
+		blend:    data[25],
+	}, nil
+}
+
+// delay converts the frame delay fraction of a second to centisec, rounding to nearest.
+func (o *apngFrame) delay() int {
+	den := int(o.delayDen)
+
+	if den == 0 {
+		den = 100
+	}
+
+	return (int(o.delayNum)*100 + den/2) / den
+}
+
+// DecodeAPNG reads an animated PNG, compositing each frame onto the canvas
+// per its disposal and blend operations.
+//
+// Plain PNGs decode as a single frame lasting DefaultImportDelay.
+func DecodeAPNG(r io.Reader) (*Animation, error) {
+	var signature [8]byte
+
+	if _, err := io.ReadFull(r, signature[:]); err != nil {
+		return nil, err
+	}
+
+	if string(signature[:]) != pngSignature {
+		return nil, fmt.Errorf("%w: not a PNG", ErrInvalidAPNG)
+	}
+
+	var ihdr []byte
+	var shared []pngChunk
+	var frames []*apngFrame
+	var defaultData []byte
+	plays := -1
+	seenData := false
+	inputSize := int64(len(signature))
+
+	for {
+		chunk, err := readPNGChunk(r)
+		if err != nil {
+			return nil, err
+		}
+
+		// Length, type, and checksum fields surround the data.
+		inputSize += int64(len(chunk.data)) + 12
+
+		if ihdr == nil && chunk.kind != "IHDR" {
+			return nil, fmt.Errorf("%w: missing IHDR", ErrInvalidAPNG)
+		}
+
+		if chunk.kind == "IEND" {
+			break
+		}
+
+		switch chunk.kind {
+		case "IHDR":
+			if len(chunk.data) != 13 {
+				return nil, fmt.Errorf("%w: IHDR length %d", ErrInvalidAPNG, len(chunk.data))
+			}
+
+			if ihdr != nil {
+				return nil, fmt.Errorf("%w: duplicate IHDR", ErrInvalidAPNG)
+			}
+
+			if width, height := binary.BigEndian.Uint32(chunk.data), binary.BigEndian.Uint32(chunk.data[4:]); width == 0 || height == 0 || uint64(width)*uint64(height) > apngMaxPixels {
+				return nil, fmt.Errorf("%w: dimensions %dx%d", ErrInvalidAPNG, width, height)
+			}
+
+			ihdr = chunk.data
+		case "acTL":
+			if len(chunk.data) != 8 {
+				return nil, fmt.Errorf("%w: acTL length %d", ErrInvalidAPNG, len(chunk.data))
+			}
+
+			plays = int(binary.BigEndian.Uint32(chunk.data[4:]))
+		case "fcTL":
+			frame, err2 := parseFrameControl(chunk.data)
+			if err2 != nil {
+				return nil, err2
+			}
+
+			frames = append(frames, frame)
+		case "IDAT":
+			seenData = true
+
+			// IDAT belongs to the animation only when an fcTL precedes it.
+			if len(frames) == 1 && !frames[0].started {
+				frames[0].data = append(frames[0].data, chunk.data...)
+				frames[0].hasData = true
+			} else {
+				defaultData = append(defaultData, chunk.data...)
+			}
+		case "fdAT":
+			seenData = true
+
+			if len(chunk.data) < 4 || len(frames) == 0 {
+				return nil, fmt.Errorf("%w: stray fdAT", ErrInvalidAPNG)
+			}
+
+			frame := frames[len(frames)-1]
+			frame.data = append(frame.data, chunk.data[4:]...)
+			frame.hasData, frame.started = true, true
+		default:
+			// Palettes, transparency, and other ancillary chunks preceding image data apply to every frame.
+			if !seenData {
+				shared = append(shared, chunk)
+			}
+		}
+
+		// Later fcTLs mark the end of the default image data.
+		if chunk.kind == "fcTL" && len(frames) > 1 {
+			frames[0].started = true
+		}
+	}
+
+	canvasWidth, canvasHeight := binary.BigEndian.Uint32(ihdr), binary.BigEndian.Uint32(ihdr[4:])
+
+	if plays < 0 || len(frames) == 0 {
+		// Plain PNG.
+		frames = []*apngFrame{{width: canvasWidth, height: canvasHeight, delayNum: DefaultImportDelay, delayDen: 100, data: defaultData, hasData: true}}
+		plays = 1
+	}
+
+	// Every frame clones the canvas, as does every dispose to previous.
+	canvases := int64(len(frames))
+
+	for _, frame := range frames[1:] {
+		if frame.dispose == apngDisposePrevious {
+			canvases++
+		}
+	}
+
+	if pixels := int64(canvasWidth) * int64(canvasHeight) * canvases; pixels > decodeBudget(inputSize) {
+		return nil, fmt.Errorf("%w: %d frames of a %dx%d canvas exceed the decoding budget", ErrInvalidAPNG, len(frames), canvasWidth, canvasHeight)
+	}
+
+	// Compositing without premultiplication preserves translucent colors exactly.
+	canvas := image.NewNRGBA(image.Rect(0, 0, int(canvasWidth), int(canvasHeight)))
+	timeline := make(Timeline, 0, len(frames))
+
+	for i, frame := range frames {
+		if !frame.hasData {
+			return nil, fmt.Errorf("%w: frame %d lacks image data", ErrInvalidAPNG, i)
+		}
+
+		if uint64(frame.x)+uint64(frame.width) > uint64(canvasWidth) || uint64(frame.y)+uint64(frame.height) > uint64(canvasHeight) {
+			return nil, fmt.Errorf("%w: frame %d exceeds the canvas", ErrInvalidAPNG, i)
+		}
+
+		img, err := decodeAPNGFrame(ihdr, shared, frame)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i, err)
+		}
+
+		area := image.Rect(int(frame.x), int(frame.y), int(frame.x+frame.width), int(frame.y+frame.height))
+		var previous *image.NRGBA
+		dispose := frame.dispose
+
+		if dispose == apngDisposePrevious {
+			if i == 0 {
+				dispose = apngDisposeBackground
+			} else {
+				previous = cloneNRGBA(canvas)
+			}
+		}
+
+		blendNRGBA(canvas, area, toNRGBA(img, img.Bounds()), frame.blend == apngBlendOver)
+		timeline = append(timeline, Frame{Image: cloneNRGBA(canvas), Delay: frame.delay()})
+
+		switch dispose {
+		case apngDisposeBackground:
+			draw.Draw(canvas, area, image.Transparent, image.Point{}, draw.Src)
+		case apngDisposePrevious:
+			canvas = previous
+		}
+	}
+
+	return &Animation{Timeline: timeline, LoopCount: loopCountForPlays(plays)}, nil
+}
+
+// cloneNRGBA deep copies an image.
+func cloneNRGBA(img *image.NRGBA) *image.NRGBA {
+	return &image.NRGBA{Pix: bytes.Clone(img.Pix), Stride: img.Stride, Rect: img.Rect}
+}
+
+// blendNRGBA draws a source image over an area of the destination,
+// either replacing the area, or alpha compositing the source over it.
+func blendNRGBA(dst *image.NRGBA, area image.Rectangle, src *image.NRGBA, over bool) {
+	for y := 0; y < area.Dy(); y++ {
+		dstRow := dst.Pix[dst.PixOffset(area.Min.X, area.Min.Y+y):][:area.Dx()*4]
+		srcRow := src.Pix[src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+y):][:area.Dx()*4]
+
+		if !over {
+			copy(dstRow, srcRow)
+			continue
+		}
+
+		for x := 0; x < len(dstRow); x += 4 {
+			sa, da := uint32(srcRow[x+3]), uint32(dstRow[x+3])
+
+			switch {
+			case sa == 0xFF || da == 0:
+				copy(dstRow[x:x+4], srcRow[x:x+4])
+			case sa == 0:
+			default:
+				// Alpha scaled by 0xFF * 0xFF.
+				a := sa*0xFF + da*(0xFF-sa)
+
+				for c := 0; c < 3; c++ {
+					dstRow[x+c] = uint8((uint32(srcRow[x+c])*sa*0xFF + uint32(dstRow[x+c])*da*(0xFF-sa) + a/2) / a)
+				}
+
+				dstRow[x+3] = uint8((a + 0x7F) / 0xFF)
+			}
+		}
+	}
+}
+
+// decodeAPNGFrame decodes the image data of a frame as a standalone PNG.
+func decodeAPNGFrame(ihdr []byte, shared []pngChunk, frame *apngFrame) (image.Image, error) {
+	var buf bytes.Buffer
+	buf.WriteString(pngSignature)
+	frameIHDR := bytes.Clone(ihdr)
+	binary.BigEndian.PutUint32(frameIHDR, frame.width)
+	binary.BigEndian.PutUint32(frameIHDR[4:], frame.height)
+
+	if err := writePNGChunk(&buf, "IHDR", frameIHDR); err != nil {
+		return nil, err
+	}
+
+	for _, chunk := range shared {
+		if err := writePNGChunk(&buf, chunk.kind, chunk.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writePNGChunk(&buf, "IDAT", frame.data); err != nil {
+		return nil, err
+	}
+
+	if err := writePNGChunk(&buf, "IEND", nil); err != nil {
+		return nil, err
+	}
+
+	return png.Decode(&buf)
+}
+
+// EncodeAPNG writes an animated PNG in 8-bit truecolor,
+// with an alpha channel when any frame holds translucent pixels.
+//
+// Each frame covers the whole canvas.
+func EncodeAPNG(w io.Writer, animation *Animation) error {
+	if len(animation.Timeline) == 0 {
+		return ErrNoFrames
+	}
+
+	bounds := animation.Bounds()
+
+	if bounds.Empty() {
+		return ErrInvalidFrame
+	}
+
+	frames := make([]*image.NRGBA, len(animation.Timeline))
+	alpha := false
+
+	for i, frame := range animation.Timeline {
+		if frame.Image == nil {
+			return fmt.Errorf("frame %d: %w", i, ErrInvalidFrame)
+		}
+
+		frames[i] = toNRGBA(frame.Image, bounds)
+		alpha = alpha || !frames[i].Opaque()
+	}
+
+	bw := bufio.NewWriter(w)
+
+	if _, err := bw.WriteString(pngSignature); err != nil {
+		return err
+	}
+
+	colorType, channels := byte(2), 3
+
+	if alpha {
+		colorType, channels = 6, 4
+	}
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr, uint32(bounds.Dx()))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(bounds.Dy()))
+	ihdr[8], ihdr[9] = 8, colorType
+
+	if err := writePNGChunk(bw, "IHDR", ihdr); err != nil {
+		return err
+	}
+
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl, uint32(len(frames)))
+	binary.BigEndian.PutUint32(actl[4:], uint32(animation.Plays()))
+
+	if err := writePNGChunk(bw, "acTL", actl); err != nil {
+		return err
+	}
+
+	var sequence uint32
+
+	for i, frame := range frames {
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl, sequence)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(bounds.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(bounds.Dy()))
+		binary.BigEndian.PutUint16(fctl[20:], uint16(min(max(animation.Timeline[i].Delay, 0), 0xFFFF)))
+		binary.BigEndian.PutUint16(fctl[22:], 100)
+		fctl[24], fctl[25] = apngDisposeNone, apngBlendSource
+		sequence++
+
+		if err := writePNGChunk(bw, "fcTL", fctl); err != nil {
+			return err
+		}
+
+		data, err := compressPNGImage(frame, channels)
+		if err != nil {
+			return err
+		}
+
+		if i == 0 {
+			err = writePNGChunk(bw, "IDAT", data)
+		} else {
+			fdat := make([]byte, 4, 4+len(data))
+			binary.BigEndian.PutUint32(fdat, sequence)
+			sequence++
+			err = writePNGChunk(bw, "fdAT", append(fdat, data...))
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := writePNGChunk(bw, "IEND", nil); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// compressPNGImage filters and compresses image rows as PNG image data,
+// choosing per row the filter with the smallest sum of absolute differences.
+func compressPNGImage(img *image.NRGBA, channels int) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	rowLen := width * channels
+	previous := make([]byte, rowLen)
+	current := make([]byte, rowLen)
+	var filtered [5][]byte
+
+	for i := range filtered {
+		filtered[i] = make([]byte, 1+rowLen)
+		filtered[i][0] = byte(i)
+	}
+
+	for y := 0; y < height; y++ {
+		pix := img.Pix[y*img.Stride : y*img.Stride+width*4]
+
+		if channels == 4 {
+			copy(current, pix)
+		} else {
+			for x := 0; x < width; x++ {
+				copy(current[x*3:x*3+3], pix[x*4:x*4+3])
+			}
+		}
+
+		best, bestSum := 0, -1
+
+		for filter := range filtered {
+			row := filtered[filter][1:]
+			sum := 0
+
+			for i := range row {
+				var left, upLeft byte
+				up := previous[i]
+
+				if i >= channels {
+					left, upLeft = current[i-channels], previous[i-channels]
+				}
+
+				var predictor byte
+
+				switch filter {
+				case 1:
+					predictor = left
+				case 2:
+					predictor = up
+				case 3:
+					predictor = byte((int(left) + int(up)) / 2)
+				case 4:
+					predictor = paeth(left, up, upLeft)
+				}
+
+				row[i] = current[i] - predictor
+				sum += int(abs8(int8(row[i])))
+			}
+
+			if bestSum < 0 || sum < bestSum {
+				best, bestSum = filter, sum
+			}
+		}
+
+		if _, err := zw.Write(filtered[best]); err != nil {
+			return nil, err
+		}
+
+		previous, current = current, previous
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// paeth implements the PNG Paeth predictor.
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := absInt(p-int(a)), absInt(p-int(b)), absInt(p-int(c))
+
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	default:
+		return c
+	}
+}
+
+// absInt computes an absolute value.
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
+
+// abs8 computes the magnitude of a signed byte.
+func abs8(x int8) int {
+	return absInt(int(x))
+}
@@ -0,0 +1,171 @@
+package buttery_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+func TestAPNGRoundTrip(t *testing.T) {
+	first := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	second := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+
+	for i := range first.Pix {
+		first.Pix[i] = uint8(i * 11)
+		second.Pix[i] = uint8(255 - i*7)
+	}
+
+	second.SetNRGBA(1, 1, color.NRGBA{R: 0x80, G: 0x40, B: 0x20, A: 0x7F})
+	sourceAnimation := &buttery.Animation{
+		Timeline: buttery.Timeline{
+			{Image: first, Delay: 4},
+			{Image: second, Delay: 6},
+		},
+		LoopCount: 2,
+	}
+
+	var buf bytes.Buffer
+
+	if err := buttery.EncodeAPNG(&buf, sourceAnimation); err != nil {
+		t.Fatal(err)
+	}
+
+	// Viewers lacking APNG support show the first frame.
+	still, err := png.Decode(bytes.NewReader(buf.Bytes()))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if still.Bounds() != first.Bounds() {
+		t.Errorf("expected still bounds %v, got %v", first.Bounds(), still.Bounds())
+	}
+
+	animation, err := buttery.DecodeAPNG(&buf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if animation.LoopCount != sourceAnimation.LoopCount {
+		t.Errorf("expected loop count %d, got %d", sourceAnimation.LoopCount, animation.LoopCount)
+	}
+
+	if delays := animation.Timeline.Delays(); !slices.Equal(delays, []int{4, 6}) {
+		t.Errorf("expected delays [4 6], got %v", delays)
+	}
+
+	for i, expected := range []*image.NRGBA{first, second} {
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 3; x++ {
+				c := color.NRGBAModel.Convert(animation.Timeline[i].Image.At(x, y))
+
+				if c != expected.NRGBAAt(x, y) {
+					t.Errorf("expected frame %d pixel (%d, %d) %v, got %v", i, x, y, expected.NRGBAAt(x, y), c)
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeAPNGPlainPNG(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 2, 2))
+	img.Pix[3] = 0xFF
+	var buf bytes.Buffer
+
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	animation, err := buttery.DecodeAPNG(&buf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(animation.Timeline) != 1 || animation.Timeline[0].Delay != buttery.DefaultImportDelay || animation.LoopCount != -1 {
+		t.Errorf("expected a single play of a single frame, got %d frames, delays %v, and loop count %d", len(animation.Timeline), animation.Timeline.Delays(), animation.LoopCount)
+	}
+}
+
+// appendPNGChunk appends a PNG chunk with its checksum.
+func appendPNGChunk(b []byte, kind string, data []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(data)))
+	b = append(b, kind...)
+	b = append(b, data...)
+	return binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(append([]byte(kind), data...)))
+}
+
+func TestDecodeAPNGRejectsOversizedInput(t *testing.T) {
+	ihdr := func(width, height uint32) []byte {
+		data := binary.BigEndian.AppendUint32(nil, width)
+		data = binary.BigEndian.AppendUint32(data, height)
+		return append(data, 8, 6, 0, 0, 0)
+	}
+
+	huge := appendPNGChunk([]byte("\x89PNG\r\n\x1a\n"), "IHDR", ihdr(60000, 60000))
+	huge = appendPNGChunk(huge, "acTL", []byte{0, 0, 0, 1, 0, 0, 0, 0})
+	huge = appendPNGChunk(huge, "IEND", nil)
+
+	if _, err := buttery.DecodeAPNG(bytes.NewReader(huge)); !errors.Is(err, buttery.ErrInvalidAPNG) {
+		t.Errorf("expected ErrInvalidAPNG for a 60000x60000 canvas, got %v", err)
+	}
+
+	truncated := appendPNGChunk([]byte("\x89PNG\r\n\x1a\n"), "IHDR", ihdr(1, 1))
+	truncated = append(truncated, 0x7F, 0xFF, 0xFF, 0xFF, 'I', 'D', 'A', 'T')
+
+	if _, err := buttery.DecodeAPNG(bytes.NewReader(truncated)); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF for a truncated chunk, got %v", err)
+	}
+}
+
+func TestDecodeAPNGRejectsFrameAmplification(t *testing.T) {
+	ihdr := binary.BigEndian.AppendUint32(nil, 8192)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, 8192)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0)
+
+	// One opaque white pixel, unfiltered.
+	var pixel bytes.Buffer
+	zw := zlib.NewWriter(&pixel)
+
+	if _, err := zw.Write([]byte{0, 0xFF, 0xFF, 0xFF, 0xFF}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	frames := 6
+	amplified := appendPNGChunk([]byte("\x89PNG\r\n\x1a\n"), "IHDR", ihdr)
+	amplified = appendPNGChunk(amplified, "acTL", binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, uint32(frames)), 0))
+	var sequence uint32
+
+	for i := 0; i < frames; i++ {
+		fctl := binary.BigEndian.AppendUint32(nil, sequence)
+		fctl = binary.BigEndian.AppendUint32(fctl, 1)
+		fctl = binary.BigEndian.AppendUint32(fctl, 1)
+		fctl = binary.BigEndian.AppendUint32(fctl, uint32(i))
+		fctl = binary.BigEndian.AppendUint32(fctl, 0)
+		fctl = append(fctl, 0, 1, 0, 10, 0, 0)
+		amplified = appendPNGChunk(amplified, "fcTL", fctl)
+		amplified = appendPNGChunk(amplified, "fdAT", append(binary.BigEndian.AppendUint32(nil, sequence+1), pixel.Bytes()...))
+		sequence += 2
+	}
+
+	amplified = appendPNGChunk(amplified, "IEND", nil)
+
+	if _, err := buttery.DecodeAPNG(bytes.NewReader(amplified)); !errors.Is(err, buttery.ErrInvalidAPNG) {
+		t.Errorf("expected ErrInvalidAPNG for %d frames of an 8192x8192 canvas from %d bytes, got %v", frames, len(amplified), err)
+	}
+}
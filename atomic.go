@@ -0,0 +1,38 @@
+package buttery
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes a file by way of a temporary sibling file,
+// renamed into place only once the write succeeds,
+// so that failures never leave a truncated file behind.
+func WriteFileAtomic(pth string, write func(w io.Writer) error) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(pth), "."+filepath.Base(pth)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	tempPth := tempFile.Name()
+
+	if err2 := write(tempFile); err2 != nil {
+		return errors.Join(err2, tempFile.Close(), os.Remove(tempPth))
+	}
+
+	if err2 := tempFile.Chmod(0644); err2 != nil {
+		return errors.Join(err2, tempFile.Close(), os.Remove(tempPth))
+	}
+
+	if err2 := tempFile.Close(); err2 != nil {
+		return errors.Join(err2, os.Remove(tempPth))
+	}
+
+	if err2 := os.Rename(tempPth, pth); err2 != nil {
+		return errors.Join(err2, os.Remove(tempPth))
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package buttery_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	pth := filepath.Join(dir, "out.gif")
+
+	if err := os.WriteFile(pth, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	errWrite := errors.New("crash")
+
+	if err := buttery.WriteFileAtomic(pth, func(w io.Writer) error {
+		if _, err := io.WriteString(w, "trunc"); err != nil {
+			return err
+		}
+
+		return errWrite
+	}); !errors.Is(err, errWrite) {
+		t.Errorf("expected write error, got %v", err)
+	}
+
+	if contents, err := os.ReadFile(pth); err != nil || string(contents) != "original" {
+		t.Errorf("expected failed writes to leave original contents, got %q %v", contents, err)
+	}
+
+	if err := buttery.WriteFileAtomic(pth, func(w io.Writer) error {
+		_, err := io.WriteString(w, "replaced")
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if contents, err := os.ReadFile(pth); err != nil || string(contents) != "replaced" {
+		t.Errorf("expected replaced contents, got %q %v", contents, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files left behind, got %d entries", len(entries))
+	}
+}
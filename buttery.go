@@ -2,40 +2,14 @@ package buttery
 
 import (
 	"image"
+	"image/gif"
 )
 
-// GetDimensions reports the horizontal and vertical bounds of a GIF.
+// GetDimensions reports the horizontal and vertical bounds of a GIF's frames,
+// measuring the same canvas as Render absent a logical screen.
 func GetDimensions(paletteds []*image.Paletted) (int, int) {
-	var xMin int
-	var xMax int
-	var yMin int
-	var yMax int
-
-	for _, paletted := range paletteds {
-		rect := paletted.Rect
-		rectXMin := rect.Min.X
-		rectXMax := rect.Max.X
-		rectYMin := rect.Min.Y
-		rectYMax := rect.Max.Y
-
-		if rectXMin < xMin {
-			xMin = rectXMin
-		}
-
-		if rectXMax > xMax {
-			xMax = rectXMax
-		}
-
-		if rectYMin < yMin {
-			yMin = rectYMin
-		}
-
-		if rectYMax > yMax {
-			yMax = rectYMax
-		}
-	}
-
-	return xMax - xMin, yMax - yMin
+	bounds := screenBounds(&gif.GIF{Image: paletteds})
+	return bounds.Dx(), bounds.Dy()
 }
 
 // GetPaletteSize queries the size of a GIF's color space.
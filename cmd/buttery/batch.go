@@ -0,0 +1,258 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// batchFlags models the options for processing many inputs.
+type batchFlags struct {
+	recursive bool
+	jobs      int
+}
+
+// register declares batch options within a flag set.
+func (o *batchFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.recursive, "r", false, fmt.Sprintf("recurse into directories, collecting %v files", strings.Join(formatExts(), "/")))
+	fs.IntVar(&o.jobs, "jobs", 1, "how many files to process concurrently")
+}
+
+// outputMarker marks buttery output files, <name>.buttery.<ext>, which batches skip as inputs.
+const outputMarker = ".buttery"
+
+// isOutput reports whether a path names a buttery output file.
+func isOutput(pth string) bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimSuffix(pth, filepath.Ext(pth))), outputMarker)
+}
+
+// batchStatus describes the outcome of one batch input.
+type batchStatus string
+
+const (
+	batchOK      batchStatus = "ok"
+	batchFailed  batchStatus = "failed"
+	batchSkipped batchStatus = "skipped"
+)
+
+// batchResult models the outcome of one batch input.
+type batchResult struct {
+	pth    string
+	status batchStatus
+	err    error
+}
+
+// isBatch reports whether operands select anything other than one plain input file.
+func (o *batchFlags) isBatch(operands []string) bool {
+	if len(operands) != 1 || o.recursive || hasGlobMeta(operands[0]) {
+		return true
+	}
+
+	info, err := os.Stat(operands[0])
+	return err == nil && info.IsDir()
+}
+
+// hasGlobMeta reports whether a path holds glob pattern syntax.
+func hasGlobMeta(pth string) bool {
+	return strings.ContainsAny(pth, `*?[`)
+}
+
+// expand resolves operands into input paths,
+// expanding globs and, with -r, directories.
+//
+// Duplicate paths are dropped.
+func (o *batchFlags) expand(operands []string) ([]string, error) {
+	var pths []string
+	seen := make(map[string]bool)
+	add := func(pth string) {
+		pth = filepath.Clean(pth)
+
+		if !seen[pth] {
+			seen[pth] = true
+			pths = append(pths, pth)
+		}
+	}
+
+	for _, operand := range operands {
+		if operand == stdio {
+			return nil, errors.New("stdin input - cannot be combined with other inputs")
+		}
+
+		matches := []string{operand}
+
+		if hasGlobMeta(operand) {
+			var err error
+			matches, err = filepath.Glob(operand)
+
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", operand, err)
+			}
+
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("%v: no matches", operand)
+			}
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if !info.IsDir() {
+				add(match)
+				continue
+			}
+
+			if !o.recursive {
+				return nil, fmt.Errorf("%v is a directory (use -r to recurse)", match)
+			}
+
+			err = filepath.WalkDir(match, func(pth string, d fs.DirEntry, err2 error) error {
+				if err2 != nil {
+					return err2
+				}
+
+				if _, ok := formatForExt(pth); ok && !d.IsDir() {
+					add(pth)
+				}
+
+				return nil
+			})
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return pths, nil
+}
+
+// run processes inputs with a pool of jobs workers,
+// skipping buttery output files.
+//
+// Results follow the order of pths.
+func (o *batchFlags) run(pths []string, process func(pth string) error) []batchResult {
+	results := make([]batchResult, len(pths))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+
+	for range max(o.jobs, 1) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indices {
+				results[i] = batchResult{pth: pths[i], status: batchOK}
+
+				if isOutput(pths[i]) {
+					results[i].status = batchSkipped
+					continue
+				}
+
+				if err := process(pths[i]); err != nil {
+					results[i].status = batchFailed
+					results[i].err = err
+				}
+			}
+		}()
+	}
+
+	for i := range pths {
+		indices <- i
+	}
+
+	close(indices)
+	wg.Wait()
+	return results
+}
+
+// summarize prints a table of batch results,
+// returning an error when any input failed.
+func summarize(w io.Writer, results []batchResult) error {
+	counts := make(map[batchStatus]int)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if _, err := fmt.Fprintln(tw, "FILE\tSTATUS\tERROR"); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		counts[result.status]++
+		var message string
+
+		if result.err != nil {
+			message = result.err.Error()
+		}
+
+		if _, err := fmt.Fprintf(tw, "%v\t%v\t%v\n", result.pth, result.status, message); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "\n%d ok, %d failed, %d skipped\n", counts[batchOK], counts[batchFailed], counts[batchSkipped]); err != nil {
+		return err
+	}
+
+	if counts[batchFailed] > 0 {
+		return fmt.Errorf("%d of %d files failed", counts[batchFailed], len(results))
+	}
+
+	return nil
+}
+
+// editAll edits one or more inputs,
+// summarizing batches on stderr.
+func editAll(ef *editFlags, bf *batchFlags, operands []string) error {
+	if ef.cacheDir != "" {
+		if err := ef.pruneCache(); err != nil {
+			return err
+		}
+	}
+
+	if ef.watch {
+		if bf.isBatch(operands) {
+			return errors.New("-watch requires a single input")
+		}
+
+		return ef.watchEdit(operands[0])
+	}
+
+	if !bf.isBatch(operands) {
+		return ef.edit(operands[0])
+	}
+
+	if ef.out != "" {
+		return errors.New("-o requires a single input; use -outTemplate for batches")
+	}
+
+	if ef.progress {
+		return errors.New("-progress requires a single input")
+	}
+
+	if ef.plan {
+		return errors.New("-plan requires a single input")
+	}
+
+	pths, err := bf.expand(operands)
+
+	if err != nil {
+		return err
+	}
+
+	return summarize(os.Stderr, bf.run(pths, ef.edit))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBatchExpand(t *testing.T) {
+	dir := t.TempDir()
+	source := encodeTestGIF(t, 3)
+
+	for _, name := range []string{"a.gif", "a.buttery.gif", "b.PNG", "sub/c.webp", "sub/d.y4m", "sub/e.apng", "sub/notes.txt"} {
+		writeTestFile(t, filepath.Join(dir, name), source)
+	}
+
+	recursive := batchFlags{recursive: true}
+	pths, err := recursive.expand([]string{dir})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+
+	for _, pth := range pths {
+		rel, err2 := filepath.Rel(dir, pth)
+
+		if err2 != nil {
+			t.Fatal(err2)
+		}
+
+		names = append(names, filepath.ToSlash(rel))
+	}
+
+	if expected := []string{"a.buttery.gif", "a.gif", "b.PNG", "sub/c.webp", "sub/d.y4m", "sub/e.apng"}; !slices.Equal(names, expected) {
+		t.Errorf("expected -r to collect %v, got %v", expected, names)
+	}
+
+	var plain batchFlags
+	pths, err = plain.expand([]string{filepath.Join(dir, "*.gif"), filepath.Join(dir, "a.gif")})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pths) != 2 {
+		t.Errorf("expected a glob to match two GIFs, deduplicating operands, got %v", pths)
+	}
+
+	if _, err := plain.expand([]string{dir}); err == nil {
+		t.Errorf("expected directories to require -r")
+	}
+
+	if _, err := plain.expand([]string{filepath.Join(dir, "*.jpg")}); err == nil {
+		t.Errorf("expected an unmatched glob to fail")
+	}
+}
+
+func TestBatchEdit(t *testing.T) {
+	dir := t.TempDir()
+	source := encodeTestGIF(t, 3)
+	writeTestFile(t, filepath.Join(dir, "good.gif"), source)
+	writeTestFile(t, filepath.Join(dir, "sub", "nested.gif"), source)
+
+	status, _, stderr := runMain(t, nil, "-r", dir)
+
+	if status != 0 || !strings.Contains(stderr, "2 ok, 0 failed, 0 skipped") {
+		t.Fatalf("expected both inputs edited, got exit status %d: %v", status, stderr)
+	}
+
+	for _, name := range []string{"good.buttery.gif", "sub/nested.buttery.gif"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected output %v, got %v", name, err)
+		}
+	}
+
+	writeTestFile(t, filepath.Join(dir, "bad.gif"), []byte("GIF89a"))
+	status, _, stderr = runMain(t, nil, "-force", filepath.Join(dir, "*.gif"))
+
+	if status != 1 || !strings.Contains(stderr, "1 ok, 1 failed, 1 skipped") || !strings.Contains(stderr, "1 of 3 files failed") {
+		t.Errorf("expected a partial failure summary and exit status 1, got exit status %d: %v", status, stderr)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "bad.buttery.gif")); !os.IsNotExist(err) {
+		t.Errorf("expected no output for the failed input, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "good.buttery.buttery.gif")); !os.IsNotExist(err) {
+		t.Errorf("expected existing outputs skipped as inputs, got %v", err)
+	}
+}
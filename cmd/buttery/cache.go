@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/mcandre/buttery"
+)
+
+// cacheEntryPattern matches cache entry file names, <version>-<key><ext>.
+var cacheEntryPattern = regexp.MustCompile(`^(.+)-[0-9a-f]{64}\.[a-z0-9]+$`)
+
+// hashInput digests an input in full,
+// returning a reader positioned back at the start of the input.
+//
+// Unseekable inputs, such as stdin, buffer in memory.
+func hashInput(r io.Reader) (io.Reader, string, error) {
+	digest := sha256.New()
+
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		if _, err := io.Copy(digest, seeker); err != nil {
+			return nil, "", err
+		}
+
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return nil, "", err
+		}
+
+		return seeker, hex.EncodeToString(digest.Sum(nil)), nil
+	}
+
+	data, err := io.ReadAll(r)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	digest.Write(data)
+	return bytes.NewReader(data), hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// cacheable reports whether a pipeline renders the same output on every run.
+//
+// Shuffle draws a fresh random ordering each run, and so bypasses the cache.
+func cacheable(pipeline buttery.Pipeline) bool {
+	for _, operation := range pipeline.Operations {
+		if stage, ok := operation.(buttery.StitchStage); ok && stage.Stitch.Is(buttery.Shuffle.Name) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// normalizeOperation fills in default stitch parameters,
+// such that explicit defaults, such as -fadeRate 1, share entries with omitted ones.
+func normalizeOperation(operation buttery.Operation) buttery.Operation {
+	stage, ok := operation.(buttery.StitchStage)
+
+	if !ok {
+		return operation
+	}
+
+	declared, _ := buttery.StitchParamsFor(stage.Stitch.Name)
+
+	for _, param := range declared {
+		if _, ok := stage.Stitch.Params[param.Name]; !ok {
+			stage.Stitch = stage.Stitch.With(param.Name, param.Default)
+		}
+	}
+
+	return stage
+}
+
+// cachePath names the cache entry for an input digest, pipeline, and output format,
+// keyed by the buttery version, the input bytes, the normalized edits, and the format.
+//
+// Normalizing configurations to pipeline stages
+// lets equivalent settings, such as -trimEdges 1 versus -trimStart 1 -trimEnd 1, share entries.
+// Workers and memory limits never affect output, and so are excluded.
+func (o *editFlags) cachePath(inputSHA256 string, pipeline buttery.Pipeline, outFormat *format) string {
+	key := sha256.New()
+	key.Write(fmt.Appendf(nil, "version=%v\ninput=%v\nformat=%v\ntransparent=%v\nloopCount=%d\n", buttery.Version, inputSHA256, outFormat.name, pipeline.Transparent, pipeline.LoopCount))
+
+	for _, operation := range pipeline.Operations {
+		key.Write(fmt.Appendf(nil, "operation=%v\n", normalizeOperation(operation)))
+	}
+
+	return filepath.Join(o.cacheDir, fmt.Sprintf("%v-%v%v", buttery.Version, hex.EncodeToString(key.Sum(nil)), outFormat.exts[0]))
+}
+
+// restoreCached copies a cache entry to the output,
+// reporting whether the entry exists.
+func (o *editFlags) restoreCached(cachePth, destPth string) (restored bool, err error) {
+	cacheFile, err := os.Open(cachePth)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	defer func() {
+		err = errors.Join(err, cacheFile.Close())
+	}()
+
+	if err2 := o.writeOutput(destPth, func(w io.Writer) error {
+		_, err3 := io.Copy(w, cacheFile)
+		return err3
+	}); err2 != nil {
+		return true, err2
+	}
+
+	// Refresh the entry age for pruning.
+	now := time.Now()
+	return true, os.Chtimes(cachePth, now, now)
+}
+
+// writeCached writes an output, saving a copy as a cache entry.
+//
+// Failed writes leave neither the output nor the cache entry behind.
+func (o *editFlags) writeCached(cachePth, destPth string, write func(w io.Writer) error) error {
+	if err := os.MkdirAll(o.cacheDir, 0755); err != nil {
+		return err
+	}
+
+	return buttery.WriteFileAtomic(cachePth, func(cacheWriter io.Writer) error {
+		return o.writeOutput(destPth, func(w io.Writer) error {
+			return write(io.MultiWriter(w, cacheWriter))
+		})
+	})
+}
+
+// pruneCache removes stale cache entries:
+// entries from other buttery versions, and entries unused for longer than -cacheMaxAge.
+//
+// Files not named like cache entries are left alone.
+func (o *editFlags) pruneCache() error {
+	entries, err := os.ReadDir(o.cacheDir)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-o.cacheMaxAge)
+	var errs []error
+
+	for _, entry := range entries {
+		match := cacheEntryPattern.FindStringSubmatch(entry.Name())
+
+		if match == nil || entry.IsDir() {
+			continue
+		}
+
+		info, err2 := entry.Info()
+
+		if err2 != nil {
+			errs = append(errs, err2)
+			continue
+		}
+
+		if match[1] != buttery.Version || (o.cacheMaxAge > 0 && info.ModTime().Before(cutoff)) {
+			if err3 := os.Remove(filepath.Join(o.cacheDir, entry.Name())); err3 != nil && !errors.Is(err3, os.ErrNotExist) {
+				errs = append(errs, err3)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
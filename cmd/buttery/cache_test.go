@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mcandre/buttery"
+)
+
+// testCachePath names the cache entry for edit flags.
+func testCachePath(t *testing.T, args ...string) (string, buttery.Pipeline) {
+	t.Helper()
+	var ef editFlags
+	fs := newFlagSet("edit", "<input.gif>")
+	ef.register(fs)
+	ef.registerOutput(fs)
+
+	if err := fs.Parse(append([]string{"-cache", t.TempDir()}, args...)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, pipeline, err := ef.config()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Base(ef.cachePath("input", pipeline, gifFormat)), pipeline
+}
+
+func TestCachePathNormalizesEdits(t *testing.T) {
+	trimEdges, _ := testCachePath(t, "-trimEdges", "1")
+	trims, _ := testCachePath(t, "-trimStart", "1", "-trimEnd", "1")
+
+	if trimEdges != trims {
+		t.Errorf("expected -trimEdges 1 to share an entry with -trimStart 1 -trimEnd 1")
+	}
+
+	fade, _ := testCachePath(t, "-stitch", "Fade")
+	fadeRate, _ := testCachePath(t, "-stitch", "Fade", "-fadeRate", "1")
+
+	if fade != fadeRate {
+		t.Errorf("expected an explicit default fade rate to share an entry")
+	}
+
+	if faster, _ := testCachePath(t, "-stitch", "Fade", "-fadeRate", "2"); faster == fade {
+		t.Errorf("expected distinct fade rates to use distinct entries")
+	}
+
+	if _, pipeline := testCachePath(t, "-stitch", "Shuffle"); cacheable(pipeline) {
+		t.Errorf("expected Shuffle to bypass the cache")
+	}
+}
+
+func TestPruneCache(t *testing.T) {
+	dir := t.TempDir()
+	key := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	fresh := buttery.Version + "-" + key + ".gif"
+	stale := buttery.Version + "-" + key + ".webp"
+	outdated := "0.0.0-" + key + ".gif"
+	unrelated := "notes.txt"
+
+	for _, name := range []string{fresh, stale, outdated, unrelated} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+
+	for _, name := range []string{stale, unrelated} {
+		if err := os.Chtimes(filepath.Join(dir, name), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ef := editFlags{cacheDir: dir, cacheMaxAge: 24 * time.Hour}
+
+	if err := ef.pruneCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, kept := range map[string]bool{fresh: true, stale: false, outdated: false, unrelated: true} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != kept {
+			t.Errorf("expected %v kept: %v, got %v", name, kept, err)
+		}
+	}
+
+	ef.cacheMaxAge = 0
+
+	if err := os.Chtimes(filepath.Join(dir, fresh), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ef.pruneCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, fresh)); err != nil {
+		t.Errorf("expected a zero -cacheMaxAge to keep current version entries, got %v", err)
+	}
+}
+
+func TestPruneCacheFormats(t *testing.T) {
+	dir := t.TempDir()
+	key := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	old := time.Now().Add(-48 * time.Hour)
+	var names []string
+
+	for _, f := range formats {
+		for _, ext := range f.exts {
+			name := "0.0.0-" + key + ext
+			pth := filepath.Join(dir, name)
+
+			if err := os.WriteFile(pth, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.Chtimes(pth, old, old); err != nil {
+				t.Fatal(err)
+			}
+
+			names = append(names, name)
+		}
+	}
+
+	ef := editFlags{cacheDir: dir, cacheMaxAge: 24 * time.Hour}
+
+	if err := ef.pruneCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %v pruned, got %v", name, err)
+		}
+	}
+}
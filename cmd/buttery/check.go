@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mcandre/buttery"
+)
+
+// checkFile validates basic GIF format file integrity.
+func checkFile(pth string) error {
+	_, err := decodeFile(pth)
+	return err
+}
+
+// severityExitStatuses keys exit statuses on the worst lint severity.
+var severityExitStatuses = map[buttery.Severity]exitStatus{
+	buttery.SeverityWarning: 2,
+	buttery.SeverityError:   3,
+}
+
+// lintReport models the JSON form of check results.
+type lintReport struct {
+	File   string            `json:"file"`
+	Worst  *buttery.Severity `json:"worst"`
+	Issues []buttery.Issue   `json:"issues"`
+}
+
+// lintFile inspects a GIF, reporting undecodable files as errors.
+func lintFile(pth string) []buttery.Issue {
+	g, err := decodeFile(pth)
+
+	if err != nil {
+		return []buttery.Issue{{Severity: buttery.SeverityError, Code: "decode", Frame: -1, Message: err.Error()}}
+	}
+
+	return buttery.Lint(g)
+}
+
+// runCheck executes the check command.
+func runCheck(args []string) error {
+	fs := newFlagSet("check", "<input.gif>")
+	asJSON := fs.Bool("json", false, "report as JSON")
+	pth, err := parseSingleInput(fs, args)
+
+	if err != nil {
+		return err
+	}
+
+	issues := lintFile(pth)
+	worst := buttery.WorstSeverity(issues)
+
+	if *asJSON {
+		report := lintReport{File: pth, Issues: issues}
+
+		if report.Issues == nil {
+			report.Issues = []buttery.Issue{}
+		}
+
+		if worst >= 0 {
+			report.Worst = &worst
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		if err2 := encoder.Encode(report); err2 != nil {
+			return err2
+		}
+	} else if len(issues) > 0 {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		if _, err2 := fmt.Fprintln(tw, "SEVERITY\tFRAME\tCODE\tMESSAGE"); err2 != nil {
+			return err2
+		}
+
+		for _, issue := range issues {
+			frame := "-"
+
+			if issue.Frame >= 0 {
+				frame = fmt.Sprint(issue.Frame)
+			}
+
+			if _, err2 := fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", issue.Severity, frame, issue.Code, issue.Message); err2 != nil {
+				return err2
+			}
+		}
+
+		if err2 := tw.Flush(); err2 != nil {
+			return err2
+		}
+	}
+
+	if status, ok := severityExitStatuses[worst]; ok {
+		return status
+	}
+
+	return nil
+}
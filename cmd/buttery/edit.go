@@ -0,0 +1,638 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image/gif"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mcandre/buttery"
+)
+
+// editFlags models the options of the edit command.
+type editFlags struct {
+	fs            *flag.FlagSet
+	transparent   bool
+	trimEdges     int
+	trimStart     int
+	trimEnd       int
+	cutInterval   int
+	window        int
+	stitch        string
+	fadeColor     string
+	fadeRate      string
+	fade          string
+	shift         int
+	scaleDelay    float64
+	panVelocity   string
+	ops           string
+	loopCount     int
+	workers       int
+	memoryLimit   int64
+	recipe        string
+	plan          bool
+	progress      bool
+	timeout       time.Duration
+	out           string
+	outTemplate   string
+	force         bool
+	format        string
+	writeRecipe   bool
+	cacheDir      string
+	cacheMaxAge   time.Duration
+	watch         bool
+	watchInterval time.Duration
+}
+
+// register declares edit options within a flag set.
+func (o *editFlags) register(fs *flag.FlagSet) {
+	o.fs = fs
+	fs.BoolVar(&o.transparent, "transparent", false, "preserve clear GIFs")
+	fs.IntVar(&o.trimEdges, "trimEdges", 0, "drop frames from both ends of the input GIF")
+	fs.IntVar(&o.trimStart, "trimStart", 0, "drop frames from start of the input GIF")
+	fs.IntVar(&o.trimEnd, "trimEnd", 0, "drop frames from end of the input GIF")
+	fs.IntVar(&o.cutInterval, "cutInterval", 0, "drop every nth frame of the input GIF")
+	fs.IntVar(&o.window, "window", 0, "set fixed sequence length")
+	fs.StringVar(&o.stitch, "stitch", "Mirror", fmt.Sprintf("stitching strategy (%s), with optional parameters (e.g. Fade:0xffffff,rate=0.5)", strings.Join(buttery.Stitches(), "/")))
+	fs.StringVar(&o.fadeColor, "fadeColor", "0x000000", "fade color (0xRRGGBB), alias for the Fade color parameter")
+	fs.StringVar(&o.fadeRate, "fadeRate", "1", "fade velocity factor, alias for the Fade rate parameter")
+	fs.StringVar(&o.fade, "fade", buttery.Fade.Name, "fade following every stitch except Shuffle and Fade, with optional parameters (None disables)")
+	fs.IntVar(&o.shift, "shift", 0, "rotate sequence left")
+	fs.Float64Var(&o.scaleDelay, "scaleDelay", 1.0, "multiply each frame delay by a factor")
+	fs.StringVar(&o.panVelocity, "panVelocity", "1", "how many pixels to pan per frame, alias for the PanH/PanV velocity parameter")
+	fs.StringVar(&o.ops, "ops", "", "ordered editing stages, overriding trims, stitch, shift, and scale delay (e.g. \"trim:2,2|mirror|fade:0xffffff\")")
+	fs.IntVar(&o.loopCount, "loopCount", 0, "how many times to play animation (-1: Once, 0: Infinite, N: N+1 iterations)")
+	fs.IntVar(&o.workers, "workers", 0, "how many frames to process concurrently (0: one per CPU)")
+	fs.Int64Var(&o.memoryLimit, "memoryLimit", 0, "stream frames, holding at most this many MiB of frames in memory and spilling the rest to a temporary file (0: no streaming)")
+	fs.StringVar(&o.recipe, "recipe", "", "load settings from a TOML (or .json) recipe file, keyed by Config field names. Explicit flags override recipe settings")
+	fs.BoolVar(&o.plan, "plan", false, "print the output frame sequence in terms of source frames, without writing anything")
+	fs.BoolVar(&o.progress, "progress", false, "show a progress bar on stderr")
+	fs.DurationVar(&o.timeout, "timeout", 0, "abort edits running longer than a duration (e.g. 30s). Zero indicates no timeout")
+}
+
+// registerOutput declares output options within a flag set.
+func (o *editFlags) registerOutput(fs *flag.FlagSet) {
+	o.registerDestination(fs)
+	fs.BoolVar(&o.writeRecipe, "writeRecipe", false, "write every setting and the input SHA-256 to a sidecar <output>.recipe.toml")
+	fs.StringVar(&o.cacheDir, "cache", "", "reuse outputs from a cache directory, keyed by input content, edits, and buttery version")
+	fs.DurationVar(&o.cacheMaxAge, "cacheMaxAge", 30*24*time.Hour, "prune cache entries unused for longer than a duration. Zero keeps entries until buttery upgrades")
+	fs.BoolVar(&o.watch, "watch", false, "regenerate the output whenever the input or recipe file changes, until interrupted")
+	fs.DurationVar(&o.watchInterval, "watchInterval", 500*time.Millisecond, "how often -watch polls file modification times")
+}
+
+// registerDestination declares output path options within a flag set.
+func (o *editFlags) registerDestination(fs *flag.FlagSet) {
+	fs.StringVar(&o.out, "o", "", "output path, or - for stdout (default: per -outTemplate, or stdout for stdin input)")
+	fs.StringVar(&o.outTemplate, "outTemplate", defaultOutTemplate, "output path template, expanding {dir}, {name}, {ext}, and {stitch} from the input path and stitch")
+	fs.BoolVar(&o.force, "force", false, "overwrite existing output files")
+	fs.StringVar(&o.format, "format", "", fmt.Sprintf("output format (%s), overriding the output path extension", strings.Join(formatNames(), "/")))
+}
+
+// defaultOutTemplate names output files <input>.buttery.gif.
+const defaultOutTemplate = "{dir}/{name}.buttery.gif"
+
+// outputFormat selects the file format of an output, per -format or else the output extension.
+//
+// Stdout defaults to GIF.
+func (o *editFlags) outputFormat(destPth string) (*format, error) {
+	if o.format != "" {
+		return formatNamed(o.format)
+	}
+
+	if destPth == stdio {
+		return gifFormat, nil
+	}
+
+	return formatForPath(destPth), nil
+}
+
+// templatePlaceholder matches output template placeholders.
+var templatePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// outputPath resolves the destination of an edit.
+//
+// - indicates stdout.
+func (o *editFlags) outputPath(sourcePth string, recipe buttery.Recipe, pipeline buttery.Pipeline) (string, error) {
+	if o.out != "" {
+		if o.outTemplate != defaultOutTemplate {
+			return "", errors.New("-o and -outTemplate are mutually exclusive")
+		}
+
+		return o.out, nil
+	}
+
+	if sourcePth == stdio {
+		if o.outTemplate != defaultOutTemplate {
+			return "", errors.New("-outTemplate requires a named input file")
+		}
+
+		return stdio, nil
+	}
+
+	// The trailing fade of a Config is not named.
+	stitches := []string{recipe.Stitch.Name}
+
+	if recipe.Operations != "" {
+		stitches = nil
+
+		for _, operation := range pipeline.Operations {
+			if stitchStage, ok := operation.(buttery.StitchStage); ok {
+				stitches = append(stitches, stitchStage.Stitch.Name)
+			}
+		}
+	}
+
+	if len(stitches) == 0 {
+		stitches = append(stitches, buttery.None.Name)
+	}
+
+	outTemplate := o.outTemplate
+
+	// The default output extension follows -format.
+	if o.format != "" && outTemplate == defaultOutTemplate {
+		outFormat, err := formatNamed(o.format)
+
+		if err != nil {
+			return "", err
+		}
+
+		outTemplate = strings.TrimSuffix(outTemplate, filepath.Ext(outTemplate)) + outFormat.exts[0]
+	}
+
+	ext := filepath.Ext(sourcePth)
+	values := map[string]string{
+		"{dir}":    filepath.Dir(sourcePth),
+		"{name}":   strings.TrimSuffix(filepath.Base(sourcePth), ext),
+		"{ext}":    ext,
+		"{stitch}": strings.Join(stitches, "-"),
+	}
+
+	var err error
+	destPth := templatePlaceholder.ReplaceAllStringFunc(outTemplate, func(placeholder string) string {
+		value, ok := values[placeholder]
+
+		if !ok && err == nil {
+			err = fmt.Errorf("unknown output template placeholder: %v", placeholder)
+		}
+
+		return value
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Clean(destPth), nil
+}
+
+// checkOutput refuses to overwrite existing files without -force.
+func (o *editFlags) checkOutput(destPth string) error {
+	if o.force || destPth == stdio {
+		return nil
+	}
+
+	_, err := os.Stat(destPth)
+
+	switch {
+	case err == nil:
+		return fmt.Errorf("%v already exists (use -force to overwrite)", destPth)
+	case errors.Is(err, os.ErrNotExist):
+		return nil
+	default:
+		return err
+	}
+}
+
+// writeOutput writes a file atomically,
+// creating parent directories as needed,
+// or else writes to stdout.
+func (o *editFlags) writeOutput(destPth string, write func(w io.Writer) error) error {
+	if destPth == stdio {
+		w := bufio.NewWriter(os.Stdout)
+
+		if err := write(w); err != nil {
+			return err
+		}
+
+		return w.Flush()
+	}
+
+	if err := o.checkOutput(destPth); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPth), 0755); err != nil {
+		return err
+	}
+
+	return buttery.WriteFileAtomic(destPth, write)
+}
+
+// config builds the configured edits.
+//
+// Explicit flags override any -recipe settings.
+func (o *editFlags) config() (buttery.Recipe, buttery.Pipeline, error) {
+	recipe := buttery.NewRecipe()
+
+	if o.recipe != "" {
+		var err error
+		recipe, err = buttery.LoadRecipe(o.recipe)
+
+		if err != nil {
+			return buttery.Recipe{}, buttery.Pipeline{}, err
+		}
+	}
+
+	explicit := make(map[string]bool)
+
+	o.fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	// set applies a flag unless a recipe supplies the setting.
+	set := func(name string, apply func()) {
+		if o.recipe == "" || explicit[name] {
+			apply()
+		}
+	}
+
+	stitch := recipe.Stitch
+	var err error
+
+	set("stitch", func() {
+		err = stitch.UnmarshalText([]byte(o.stitch))
+	})
+
+	if err != nil {
+		return buttery.Recipe{}, buttery.Pipeline{}, err
+	}
+
+	fade := recipe.Fade
+
+	set("fade", func() {
+		err = fade.UnmarshalText([]byte(o.fade))
+	})
+
+	if err != nil {
+		return buttery.Recipe{}, buttery.Pipeline{}, err
+	}
+
+	// Legacy stitch parameter flags apply to stitches declaring the parameter,
+	// and otherwise to the trailing fade.
+	stitchParamFlags := map[string]struct {
+		param string
+		value string
+	}{
+		"fadeColor":   {param: "color", value: o.fadeColor},
+		"fadeRate":    {param: "rate", value: o.fadeRate},
+		"panVelocity": {param: "velocity", value: o.panVelocity},
+	}
+
+	stitchParams, _ := buttery.StitchParamsFor(stitch.Name)
+	fadeParams, _ := buttery.StitchParamsFor(fade.Name)
+
+	// declares reports whether a stitch declares a parameter.
+	declares := func(params []buttery.StitchParam, name string) bool {
+		return slices.ContainsFunc(params, func(param buttery.StitchParam) bool { return param.Name == name })
+	}
+
+	o.fs.Visit(func(f *flag.Flag) {
+		stitchParamFlag, ok := stitchParamFlags[f.Name]
+
+		switch {
+		case !ok:
+		case declares(stitchParams, stitchParamFlag.param):
+			stitch = stitch.With(stitchParamFlag.param, stitchParamFlag.value)
+		case declares(fadeParams, stitchParamFlag.param):
+			fade = fade.With(stitchParamFlag.param, stitchParamFlag.value)
+		}
+	})
+
+	recipe.Stitch = stitch
+	recipe.Fade = fade
+	set("transparent", func() { recipe.Transparent = o.transparent })
+	set("trimEdges", func() { recipe.TrimEdges = o.trimEdges })
+	set("trimStart", func() { recipe.TrimStart = o.trimStart })
+	set("trimEnd", func() { recipe.TrimEnd = o.trimEnd })
+	set("cutInterval", func() { recipe.CutInterval = o.cutInterval })
+	set("window", func() { recipe.Window = o.window })
+	set("shift", func() { recipe.Shift = o.shift })
+	set("scaleDelay", func() { recipe.ScaleDelay = o.scaleDelay })
+	set("loopCount", func() { recipe.LoopCount = o.loopCount })
+	set("workers", func() { recipe.Workers = o.workers })
+	set("memoryLimit", func() { recipe.MemoryLimit = o.memoryLimit << 20 })
+	set("ops", func() { recipe.Operations = o.ops })
+
+	if err2 := recipe.Validate(); err2 != nil {
+		return buttery.Recipe{}, buttery.Pipeline{}, err2
+	}
+
+	pipeline, err := recipe.Pipeline()
+
+	if err != nil {
+		return buttery.Recipe{}, buttery.Pipeline{}, err
+	}
+
+	return recipe, pipeline, nil
+}
+
+// context generates a context honoring the configured timeout and progress options.
+func (o *editFlags) context() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+
+	if o.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+	}
+
+	if o.progress {
+		ctx = buttery.WithProgress(ctx, progressBar)
+	}
+
+	return ctx, cancel
+}
+
+// decode reads an input of any supported format in full,
+// checking the configured edits against its frames.
+func (o *editFlags) decode(recipe buttery.Recipe, r io.Reader) (source, error) {
+	br := bufio.NewReader(r)
+	inFormat, err := sniffFormat(br)
+
+	if err != nil {
+		return source{}, err
+	}
+
+	src := source{format: inFormat}
+
+	if inFormat == gifFormat {
+		src.gif, err = gif.DecodeAll(br)
+	} else {
+		src.animation, err = inFormat.decode(br)
+	}
+
+	if err != nil {
+		return source{}, err
+	}
+
+	if recipe.Operations == "" {
+		if src.gif != nil {
+			err = recipe.ValidateFor(src.gif)
+		} else {
+			err = recipe.ValidateForAnimation(src.animation)
+		}
+	}
+
+	return src, err
+}
+
+// recipePath names the sidecar recipe of an output file, <output>.recipe.toml.
+func recipePath(destPth string) string {
+	return strings.TrimSuffix(destPth, filepath.Ext(destPth)) + ".recipe.toml"
+}
+
+// printPlan reports the output frame sequence of an edit in terms of source frames.
+func (o *editFlags) printPlan(recipe buttery.Recipe, pipeline buttery.Pipeline, sourcePth string) (err error) {
+	sourceFile, err := openInput(sourcePth)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, sourceFile.Close())
+	}()
+
+	src, err := o.decode(recipe, sourceFile)
+
+	if err != nil {
+		return err
+	}
+
+	sourceGif, err := src.asGIF()
+
+	if err != nil {
+		return err
+	}
+
+	plan, err := pipeline.Plan(sourceGif)
+
+	if err != nil {
+		return err
+	}
+
+	var sequence []string
+	var delay int
+
+	for _, frame := range plan {
+		sequence = append(sequence, frame.String())
+		delay += frame.Delay
+	}
+
+	fmt.Printf("sequence: %v\n", strings.Join(sequence, " "))
+	fmt.Printf("frames: %d -> %d\n", len(sourceGif.Image), len(plan))
+	fmt.Printf("duration: %v\n", centiseconds(delay))
+	fmt.Println()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	if _, err2 := fmt.Fprintln(tw, "OUTPUT\tSOURCE\tEFFECTS\tDELAY"); err2 != nil {
+		return err2
+	}
+
+	for i, frame := range plan {
+		source, effects := "?", "-"
+
+		if frame.Source >= 0 {
+			source = fmt.Sprint(frame.Source)
+		}
+
+		if len(frame.Effects) > 0 {
+			effects = strings.Join(frame.Effects, " ")
+		}
+
+		if _, err2 := fmt.Fprintf(tw, "%d\t%v\t%v\t%v\n", i, source, effects, centiseconds(frame.Delay)); err2 != nil {
+			return err2
+		}
+	}
+
+	return tw.Flush()
+}
+
+// stream edits a GIF with bounded memory, see buttery.Pipeline.EditToContext.
+func (o *editFlags) stream(ctx context.Context, pipeline buttery.Pipeline, r io.Reader, outFormat *format, write func(encode func(w io.Writer) error) error) error {
+	br := bufio.NewReader(r)
+	inFormat, err := sniffFormat(br)
+
+	if err != nil {
+		return err
+	}
+
+	if inFormat != gifFormat || outFormat != gifFormat {
+		return errors.New("-memoryLimit requires GIF input and output")
+	}
+
+	return write(func(w io.Writer) error {
+		return pipeline.EditToContext(ctx, w, br)
+	})
+}
+
+// edit generates an output GIF, <input>.buttery.gif by default.
+func (o *editFlags) edit(sourcePth string) (err error) {
+	recipe, pipeline, err := o.config()
+
+	if err != nil {
+		return err
+	}
+
+	if o.plan {
+		return o.printPlan(recipe, pipeline, sourcePth)
+	}
+
+	destPth, err := o.outputPath(sourcePth, recipe, pipeline)
+
+	if err != nil {
+		return err
+	}
+
+	if err2 := o.checkOutput(destPth); err2 != nil {
+		return err2
+	}
+
+	outFormat, err := o.outputFormat(destPth)
+
+	if err != nil {
+		return err
+	}
+
+	if o.writeRecipe {
+		if destPth == stdio {
+			return errors.New("-writeRecipe requires a named output file")
+		}
+
+		if err2 := o.checkOutput(recipePath(destPth)); err2 != nil {
+			return err2
+		}
+	}
+
+	sourceFile, err := openInput(sourcePth)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, sourceFile.Close())
+	}()
+
+	var r io.Reader = sourceFile
+	var inputSHA256, cachePth string
+
+	if o.cacheDir != "" || o.writeRecipe {
+		r, inputSHA256, err = hashInput(sourceFile)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	// write saves the output, along with a cache entry when caching.
+	write := func(encode func(w io.Writer) error) error {
+		if cachePth == "" {
+			return o.writeOutput(destPth, encode)
+		}
+
+		return o.writeCached(cachePth, destPth, encode)
+	}
+
+	var cached bool
+
+	if o.cacheDir != "" && cacheable(pipeline) {
+		cachePth = o.cachePath(inputSHA256, pipeline, outFormat)
+		cached, err = o.restoreCached(cachePth, destPth)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if !cached {
+		ctx, cancel := o.context()
+		defer cancel()
+
+		if recipe.MemoryLimit > 0 {
+			err = o.stream(ctx, pipeline, r, outFormat, write)
+		} else {
+			var src source
+			src, err = o.decode(recipe, r)
+
+			if err == nil {
+				src, err = src.edit(ctx, pipeline, outFormat)
+			}
+
+			if err == nil {
+				err = write(func(w io.Writer) error {
+					return src.encode(w, outFormat)
+				})
+			}
+		}
+
+		if o.progress {
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+
+	if err != nil || !o.writeRecipe {
+		return err
+	}
+
+	recipe.Input = sourcePth
+	recipe.InputSHA256 = inputSHA256
+	recipe.Version = buttery.Version
+	return o.writeOutput(recipePath(destPth), recipe.EncodeTOML)
+}
+
+// runEdit executes the edit command.
+func runEdit(args []string) error {
+	var ef editFlags
+	var bf batchFlags
+	fs := newFlagSet("edit", "<input.gif|dir|glob>...")
+	ef.register(fs)
+	ef.registerOutput(fs)
+	bf.register(fs)
+
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	operands := fs.Args()
+
+	if len(operands) == 0 || slices.Contains(operands, "") {
+		fs.Usage()
+		return errUsage
+	}
+
+	return editAll(&ef, &bf, operands)
+}
+
+// progressBar renders progress reports to stderr.
+func progressBar(progress buttery.Progress) {
+	const width = 20
+
+	if progress.Frames < 1 {
+		fmt.Fprintf(os.Stderr, "\r[%s] %-9s %d", strings.Repeat("?", width), progress.Stage, progress.Done)
+		return
+	}
+
+	done := width * min(progress.Done, progress.Frames) / progress.Frames
+	bar := strings.Repeat("#", done) + strings.Repeat(".", width-done)
+	fmt.Fprintf(os.Stderr, "\r[%s] %-9s %d/%d", bar, progress.Stage, progress.Done, progress.Frames)
+}
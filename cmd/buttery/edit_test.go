@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPipeline renders the pipeline stages of edit flags.
+func testPipeline(t *testing.T, args ...string) string {
+	t.Helper()
+	var ef editFlags
+	fs := newFlagSet("edit", "<input.gif>")
+	ef.register(fs)
+
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	_, pipeline, err := ef.config()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fmt.Sprint(pipeline.Operations)
+}
+
+func TestConfigTrailingFade(t *testing.T) {
+	for _, tc := range []struct {
+		args     []string
+		expected string
+	}{
+		{expected: "[Mirror Fade delay:1]"},
+		{args: []string{"-fadeColor", "0xffffff"}, expected: "[Mirror Fade:color=0xffffff delay:1]"},
+		{args: []string{"-stitch", "Fade", "-fadeRate", "2"}, expected: "[Fade:rate=2 delay:1]"},
+		{args: []string{"-fade", "None"}, expected: "[Mirror delay:1]"},
+	} {
+		if operations := testPipeline(t, tc.args...); operations != tc.expected {
+			t.Errorf("expected %v to plan %v, got %v", tc.args, tc.expected, operations)
+		}
+	}
+}
+
+func TestEditStdio(t *testing.T) {
+	dir := t.TempDir()
+	source := encodeTestGIF(t, 3)
+	pth := filepath.Join(dir, "input.gif")
+	destPth := filepath.Join(dir, "output.gif")
+	writeTestFile(t, pth, source)
+
+	if status, _, stderr := runMain(t, nil, "-o", destPth, pth); status != 0 {
+		t.Fatalf("expected a file output, got exit status %d: %v", status, stderr)
+	}
+
+	expected, err := os.ReadFile(destPth)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		stdin []byte
+		args  []string
+	}{
+		{stdin: source, args: []string{"-"}},
+		{stdin: source, args: []string{"edit", "-"}},
+		{args: []string{"-o", "-", pth}},
+		{stdin: source, args: []string{"-o", "-", "-"}},
+	} {
+		status, stdout, stderr := runMain(t, tc.stdin, tc.args...)
+
+		if status != 0 {
+			t.Errorf("%v: expected exit status 0, got %d: %v", tc.args, status, stderr)
+			continue
+		}
+
+		if !bytes.Equal([]byte(stdout), expected) {
+			t.Errorf("%v: expected stdout to match the file output", tc.args)
+		}
+	}
+
+	g, err := gif.DecodeAll(bytes.NewReader(expected))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.Image) != 5 {
+		t.Errorf("expected a 5 frame mirror loop, got %d frames", len(g.Image))
+	}
+
+	if status, stdout, _ := runMain(t, source, "frames", "-"); status != 0 || stdout != "3\n" {
+		t.Errorf("expected 3 frames counted from stdin, got exit status %d and %q", status, stdout)
+	}
+
+	if status, stdout, stderr := runMain(t, []byte("GIF89a"), "-"); status != 1 || stdout != "" || !strings.Contains(stderr, "unexpected EOF") {
+		t.Errorf("expected corrupt stdin to fail on stderr alone, got exit status %d, stdout %q, and stderr %q", status, stdout, stderr)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image/gif"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mcandre/buttery"
+)
+
+// format models an animation file format.
+type format struct {
+	// name identifies the format for -format.
+	name string
+
+	// exts lists the file extensions of the format, the first being the default.
+	exts []string
+
+	// magic reports whether leading bytes mark a file in the format.
+	magic func(header []byte) bool
+
+	// decode reads a truecolor animation. GIF leaves this nil, decoding as paletted frames.
+	decode func(r io.Reader) (*buttery.Animation, error)
+
+	// encode writes a truecolor animation. GIF leaves this nil, encoding paletted frames.
+	encode func(w io.Writer, animation *buttery.Animation) error
+}
+
+// gifFormat denotes GIF, the default format.
+var gifFormat = &format{name: "gif", exts: []string{".gif"}, magic: hasPrefix("GIF8")}
+
+// formats lists the supported file formats.
+var formats = []*format{
+	gifFormat,
+	{name: "apng", exts: []string{".png", ".apng"}, magic: hasPrefix("\x89PNG\r\n\x1a\n"), decode: buttery.DecodeAPNG, encode: buttery.EncodeAPNG},
+	{name: "webp", exts: []string{".webp"}, magic: isWebP, decode: buttery.DecodeWebP, encode: buttery.EncodeWebP},
+	{name: "y4m", exts: []string{".y4m"}, magic: hasPrefix("YUV4MPEG2 "), decode: buttery.DecodeY4M, encode: buttery.EncodeY4M},
+}
+
+// sniffLength bounds the leading bytes needed to identify a format.
+const sniffLength = 12
+
+// hasPrefix matches leading bytes against a fixed signature.
+func hasPrefix(signature string) func(header []byte) bool {
+	return func(header []byte) bool {
+		return bytes.HasPrefix(header, []byte(signature))
+	}
+}
+
+// isWebP matches the RIFF header of WebP files, RIFF<size>WEBP.
+func isWebP(header []byte) bool {
+	return len(header) >= 12 && string(header[:4]) == "RIFF" && string(header[8:12]) == "WEBP"
+}
+
+// formatNames lists the supported file format names.
+func formatNames() []string {
+	var names []string
+
+	for _, f := range formats {
+		names = append(names, f.name)
+	}
+
+	return names
+}
+
+// formatNamed looks up a file format by name.
+func formatNamed(name string) (*format, error) {
+	for _, f := range formats {
+		if strings.EqualFold(f.name, name) {
+			return f, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown format: %v (want %v)", name, strings.Join(formatNames(), "/"))
+}
+
+// formatForPath selects a file format by extension, defaulting to GIF.
+func formatForPath(pth string) *format {
+	if f, ok := formatForExt(pth); ok {
+		return f
+	}
+
+	return gifFormat
+}
+
+// formatForExt looks up a file format by extension.
+func formatForExt(pth string) (*format, bool) {
+	ext := strings.ToLower(filepath.Ext(pth))
+
+	for _, f := range formats {
+		if slices.Contains(f.exts, ext) {
+			return f, true
+		}
+	}
+
+	return nil, false
+}
+
+// formatExts lists the file extensions of every supported format.
+func formatExts() []string {
+	var exts []string
+
+	for _, f := range formats {
+		exts = append(exts, f.exts...)
+	}
+
+	return exts
+}
+
+// sniffFormat identifies the format of an input by its leading bytes.
+func sniffFormat(r *bufio.Reader) (*format, error) {
+	header, err := r.Peek(sniffLength)
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	for _, f := range formats {
+		if f.magic(header) {
+			return f, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unrecognized input format (want %v)", strings.Join(formatNames(), "/"))
+}
+
+// source models a decoded input, either paletted GIF frames or a truecolor animation.
+type source struct {
+	format    *format
+	gif       *gif.GIF
+	animation *buttery.Animation
+}
+
+// frames counts the input frames.
+func (o source) frames() int {
+	if o.gif != nil {
+		return len(o.gif.Image)
+	}
+
+	return len(o.animation.Timeline)
+}
+
+// delays lists the frame durations in centisec.
+func (o source) delays() []int {
+	if o.gif != nil {
+		return o.gif.Delay
+	}
+
+	return o.animation.Timeline.Delays()
+}
+
+// asGIF converts the input to a GIF as needed.
+func (o source) asGIF() (*gif.GIF, error) {
+	if o.gif != nil {
+		return o.gif, nil
+	}
+
+	return o.animation.GIF()
+}
+
+// asAnimation converts the input to a truecolor animation as needed.
+func (o source) asAnimation() (*buttery.Animation, error) {
+	if o.animation != nil {
+		return o.animation, nil
+	}
+
+	return buttery.NewAnimation(o.gif)
+}
+
+// edit applies a pipeline, in truecolor for formats other than GIF.
+func (o source) edit(ctx context.Context, pipeline buttery.Pipeline, outFormat *format) (source, error) {
+	if outFormat == gifFormat {
+		sourceGif, err := o.asGIF()
+
+		if err != nil {
+			return source{}, err
+		}
+
+		butteryGif, err := pipeline.EditGIFContext(ctx, sourceGif)
+		return source{format: outFormat, gif: butteryGif}, err
+	}
+
+	sourceAnimation, err := o.asAnimation()
+
+	if err != nil {
+		return source{}, err
+	}
+
+	butteryAnimation, err := pipeline.EditAnimationContext(ctx, sourceAnimation)
+	return source{format: outFormat, animation: butteryAnimation}, err
+}
+
+// encode writes the frames in a file format.
+func (o source) encode(w io.Writer, outFormat *format) error {
+	if outFormat == gifFormat {
+		g, err := o.asGIF()
+
+		if err != nil {
+			return err
+		}
+
+		return gif.EncodeAll(w, g)
+	}
+
+	animation, err := o.asAnimation()
+
+	if err != nil {
+		return err
+	}
+
+	return outFormat.encode(w, animation)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mcandre/buttery"
+)
+
+// countFrames prints the total GIF frame count.
+func countFrames(pth string) error {
+	g, err := decodeFile(pth)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(len(g.Image))
+	return nil
+}
+
+// framesSubcommands indexes the frames subcommands by name.
+var framesSubcommands = map[string]func(args []string) error{
+	"export": runFramesExport,
+	"import": runFramesImport,
+}
+
+// runFrames executes the frames command.
+func runFrames(args []string) error {
+	if len(args) > 0 {
+		if run, ok := framesSubcommands[args[0]]; ok {
+			return run(args[1:])
+		}
+	}
+
+	fs := newFlagSet("frames", "<input.gif>")
+	fs.Usage = func() {
+		program := programName()
+		fmt.Fprintf(os.Stderr, "Usage: %v frames <input.gif>\n", program)
+		fmt.Fprintf(os.Stderr, "       %v frames export [OPTION] <input>\n", program)
+		fmt.Fprintf(os.Stderr, "       %v frames import [OPTION] <dir>\n", program)
+	}
+
+	pth, err := parseSingleInput(fs, args)
+
+	if err != nil {
+		return err
+	}
+
+	return countFrames(pth)
+}
+
+// runFramesExport executes the frames export command,
+// applying any edit options before rendering the frames.
+func runFramesExport(args []string) (err error) {
+	var ef editFlags
+	fs := newFlagSet("frames export", "<input>")
+
+	if err2 := ef.registerUnstitched(fs); err2 != nil {
+		return err2
+	}
+
+	fs.StringVar(&ef.out, "o", "", "output directory (default: <input>.frames)")
+	fs.BoolVar(&ef.force, "force", false, "overwrite an existing frame sequence")
+	pth, err := parseSingleInput(fs, args)
+
+	if err != nil {
+		return err
+	}
+
+	dir := ef.out
+
+	if dir == "" {
+		if pth == stdio {
+			return errors.New("-o is required for stdin input")
+		}
+
+		dir = strings.TrimSuffix(pth, filepath.Ext(pth)) + ".frames"
+	}
+
+	if err2 := ef.checkOutput(filepath.Join(dir, buttery.ManifestName)); err2 != nil {
+		return err2
+	}
+
+	recipe, pipeline, err := ef.config()
+
+	if err != nil {
+		return err
+	}
+
+	sourceFile, err := openInput(pth)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, sourceFile.Close())
+	}()
+
+	src, err := ef.decode(recipe, sourceFile)
+
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := ef.context()
+	defer cancel()
+	edited, err := src.edit(ctx, pipeline, gifFormat)
+
+	if ef.progress {
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return buttery.ExportFrames(dir, edited.gif)
+}
+
+// registerUnstitched declares edit options whose stitch and fade default to None,
+// keeping the sequence as is unless asked otherwise.
+func (o *editFlags) registerUnstitched(fs *flag.FlagSet) error {
+	o.register(fs)
+
+	for _, name := range []string{"stitch", "fade"} {
+		f := fs.Lookup(name)
+		f.DefValue = buttery.None.Name
+
+		if err := f.Value.Set(buttery.None.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// registerImport declares the edit and destination options of import commands, see registerUnstitched.
+func (o *editFlags) registerImport(fs *flag.FlagSet) error {
+	if err := o.registerUnstitched(fs); err != nil {
+		return err
+	}
+
+	o.registerDestination(fs)
+	return nil
+}
+
+// editImport applies edit options to an imported animation, writing the output.
+//
+// The imported loop count applies unless -loopCount or -recipe is given.
+func (o *editFlags) editImport(sourcePth string, sourceAnimation *buttery.Animation) error {
+	recipe, pipeline, err := o.config()
+
+	if err != nil {
+		return err
+	}
+
+	explicit := make(map[string]bool)
+
+	o.fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	if !explicit["loopCount"] && o.recipe == "" {
+		pipeline.LoopCount = sourceAnimation.LoopCount
+	}
+
+	if recipe.Operations == "" {
+		if err2 := recipe.ValidateForAnimation(sourceAnimation); err2 != nil {
+			return err2
+		}
+	}
+
+	destPth, err := o.outputPath(sourcePth, recipe, pipeline)
+
+	if err != nil {
+		return err
+	}
+
+	if err2 := o.checkOutput(destPth); err2 != nil {
+		return err2
+	}
+
+	outFormat, err := o.outputFormat(destPth)
+
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := o.context()
+	defer cancel()
+	edited, err := source{animation: sourceAnimation}.edit(ctx, pipeline, outFormat)
+
+	if o.progress {
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return o.writeOutput(destPth, func(w io.Writer) error {
+		return edited.encode(w, outFormat)
+	})
+}
+
+// runFramesImport executes the frames import command,
+// applying any edit options to the assembled GIF.
+func runFramesImport(args []string) error {
+	var ef editFlags
+	fs := newFlagSet("frames import", "<dir>")
+
+	if err := ef.registerImport(fs); err != nil {
+		return err
+	}
+
+	dir, err := parseSingleInput(fs, args)
+
+	if err != nil {
+		return err
+	}
+
+	sourceAnimation, err := buttery.ImportAnimation(dir)
+
+	if err != nil {
+		return err
+	}
+
+	return ef.editImport(filepath.Clean(dir), sourceAnimation)
+}
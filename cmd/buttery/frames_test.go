@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+// readTestManifest loads the manifest of an exported frame sequence.
+func readTestManifest(t *testing.T, dir string) buttery.FrameManifest {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, buttery.ManifestName))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var manifest buttery.FrameManifest
+
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	return manifest
+}
+
+func TestFramesExportEdits(t *testing.T) {
+	dir := t.TempDir()
+	sourcePth := filepath.Join(dir, "input.gif")
+	writeTestFile(t, sourcePth, encodeTestGIF(t, 3))
+
+	for _, tc := range []struct {
+		args   []string
+		frames int
+		delay  int
+	}{
+		{frames: 3, delay: 4},
+		{args: []string{"-trimStart", "1", "-stitch", "Mirror", "-fade", "None", "-scaleDelay", "2"}, frames: 3, delay: 8},
+	} {
+		framesDir := filepath.Join(t.TempDir(), "frames")
+		args := append(append([]string{"frames", "export", "-o", framesDir}, tc.args...), sourcePth)
+
+		if status, _, stderr := runMain(t, nil, args...); status != 0 {
+			t.Fatalf("expected %v to export, got exit status %d: %v", tc.args, status, stderr)
+		}
+
+		manifest := readTestManifest(t, framesDir)
+
+		if len(manifest.Frames) != tc.frames {
+			t.Errorf("expected %v to export %d frames, got %d", tc.args, tc.frames, len(manifest.Frames))
+		}
+
+		for _, frame := range manifest.Frames {
+			if frame.Delay != tc.delay {
+				t.Errorf("expected %v to export delay %d, got %d", tc.args, tc.delay, frame.Delay)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mcandre/buttery"
+)
+
+// disposalNames labels GIF disposal methods.
+var disposalNames = map[byte]string{
+	0: "unspecified",
+	1: "none",
+	2: "background",
+	3: "previous",
+}
+
+// disposalName labels a GIF disposal method.
+func disposalName(disposal byte) string {
+	if name, ok := disposalNames[disposal]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("reserved (%d)", disposal)
+}
+
+// centiseconds converts GIF delays to durations.
+func centiseconds(delay int) time.Duration {
+	return time.Duration(delay) * 10 * time.Millisecond
+}
+
+// printInfo renders GIF metadata as a summary and per-frame table.
+func printInfo(info *buttery.Info, paletteSize int) error {
+	fmt.Printf("dimensions: %dx%d\n", info.Width, info.Height)
+	fmt.Printf("frames: %d\n", len(info.Frames))
+	fmt.Printf("duration: %v\n", centiseconds(info.Duration))
+	fmt.Printf("palette size: %d\n", paletteSize)
+	fmt.Printf("global palette size: %d\n", info.GlobalPaletteSize)
+	fmt.Printf("background index: %d\n", info.BackgroundIndex)
+	fmt.Printf("loop count: %d\n", info.LoopCount)
+	fmt.Println()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	if _, err := fmt.Fprintln(tw, "FRAME\tBOUNDS\tDELAY\tDISPOSAL\tPALETTE\tTRANSPARENT"); err != nil {
+		return err
+	}
+
+	for i, frame := range info.Frames {
+		transparent := "-"
+
+		if frame.TransparentIndex >= 0 {
+			transparent = fmt.Sprint(frame.TransparentIndex)
+		}
+
+		if _, err := fmt.Fprintf(tw, "%d\t%dx%d+%d+%d\t%v\t%v\t%d\t%v\n", i, frame.Width, frame.Height, frame.X, frame.Y, centiseconds(frame.Delay), disposalName(frame.Disposal), frame.PaletteSize, transparent); err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
+
+// runInfo executes the info command.
+func runInfo(args []string) error {
+	fs := newFlagSet("info", "<input.gif>")
+	asJSON := fs.Bool("json", false, "report as JSON")
+	pth, err := parseSingleInput(fs, args)
+
+	if err != nil {
+		return err
+	}
+
+	g, err := decodeFile(pth)
+
+	if err != nil {
+		return err
+	}
+
+	info := buttery.GetInfo(g)
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(info)
+	}
+
+	return printInfo(&info, buttery.GetPaletteSize(g.Image))
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"image/gif"
+	"io"
+	"os"
+)
+
+// stdio denotes stdin as an input path, or stdout as an output path.
+const stdio = "-"
+
+// openInput opens an input file, or stdin for -.
+func openInput(pth string) (io.ReadCloser, error) {
+	if pth == stdio {
+		return io.NopCloser(os.Stdin), nil
+	}
+
+	return os.Open(pth)
+}
+
+// decodeFile reads a GIF in full.
+func decodeFile(pth string) (g *gif.GIF, err error) {
+	f, err := openInput(pth)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+
+	return gif.DecodeAll(f)
+}
@@ -2,161 +2,220 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"image/color"
-	"image/gif"
 	"os"
-	"path/filepath"
-	"strconv"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/mcandre/buttery"
 )
 
-var flagCheck = flag.Bool("check", false, "validate basic GIF format file integrity")
-var flagGetFrames = flag.Bool("getFrames", false, "query total input GIF frame count")
-var flagTransparent = flag.Bool("transparent", false, "preserve clear GIFs")
-var flagTrimEdges = flag.Int("trimEdges", 0, "drop frames from both ends of the input GIF")
-var flagTrimStart = flag.Int("trimStart", 0, "drop frames from start of the input GIF")
-var flagTrimEnd = flag.Int("trimEnd", 0, "drop frames from end of the input GIF")
-var flagCutInterval = flag.Int("cutInterval", 0, "drop every nth frame of the input GIF")
-var flagWindow = flag.Int("window", 0, "set fixed sequence length")
-var flagStitch = flag.String("stitch", "Mirror", "stitching strategy (None/Mirror/FlipH/FlipV/Shuffle/PanH/PanV/Fade)")
-var flagFadeColor = flag.String("fadeColor", "0x000000", "fade color (0xRRGGBB)")
-var flagFadeRate = flag.Float64("fadeRate", 1.0, "fade velocity factor")
-var flagShift = flag.Int("shift", 0, "rotate sequence left")
-var flagScaleDelay = flag.Float64("scaleDelay", 1.0, "multiply each frame delay by a factor")
-var flagPanVelocity = flag.Float64("panVelocity", 1, "how many pixels to pan per frame")
-var flagLoopCount = flag.Int("loopCount", 0, "how many times to play animation (-1: Once, 0: Infinite, N: N+1 iterations)")
-var flagVersion = flag.Bool("version", false, "show version information")
-var flagHelp = flag.Bool("help", false, "show usage information")
-
-func usage() {
+// command models a subcommand.
+type command struct {
+	// summary briefly describes the subcommand.
+	summary string
+
+	// run executes the subcommand with the arguments following its name.
+	run func(args []string) error
+}
+
+// commands indexes subcommands by name.
+var commands = map[string]command{
+	"edit":    {summary: "generate a continuous loop <input>.buttery.gif (default)", run: runEdit},
+	"info":    {summary: "report GIF metadata", run: runInfo},
+	"check":   {summary: "lint GIFs, reporting problems by severity", run: runCheck},
+	"frames":  {summary: "query total GIF frame count", run: runFrames},
+	"preview": {summary: "summarize the result of an edit without writing files", run: runPreview},
+	"sprite":  {summary: "export and import sprite sheets", run: runSprite},
+}
+
+// programName queries the executable path for usage messages.
+func programName() string {
 	program, err := os.Executable()
 
 	if err != nil {
-		fmt.Fprint(os.Stderr, err)
-		os.Exit(1)
+		return "buttery"
 	}
 
-	fmt.Printf("Usage: %v [OPTION] <input.gif>\n", program)
-	flag.PrintDefaults()
+	return program
 }
 
-func main() {
-	flag.Parse()
+// usageText renders usage information.
+//
+// Usage accompanying errors belongs on stderr,
+// keeping stdout clean for piped output.
+func usageText() string {
+	var b strings.Builder
+	program := programName()
+	fmt.Fprintf(&b, "Usage: %v <command> [OPTION] <input.gif>\n", program)
+	fmt.Fprintf(&b, "       %v [OPTION] <input.gif|dir|glob>...\n\n", program)
+	fmt.Fprintf(&b, "Input - denotes stdin.\n\n")
+	fmt.Fprintln(&b, "Commands:")
 
-	if *flagHelp {
-		usage()
-		os.Exit(0)
-	}
+	var names []string
 
-	if *flagVersion {
-		fmt.Println(buttery.Version)
-		os.Exit(0)
+	for name := range commands {
+		names = append(names, name)
 	}
 
-	rest := flag.Args()
+	sort.Strings(names)
 
-	if len(rest) != 1 {
-		usage()
-		os.Exit(1)
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-9s %s\n", name, commands[name].summary)
 	}
 
-	sourcePth := rest[0]
+	fmt.Fprintf(&b, "\nSee %v <command> -help for command options.\n", program)
+	fmt.Fprintln(&b, "\nLegacy options, which edit unless -check or -getFrames is given:")
+	fs := legacyFlagSet(&editFlags{}, &batchFlags{}, new(bool), new(bool), new(bool), new(bool))
+	fs.SetOutput(&b)
+	fs.PrintDefaults()
+	return b.String()
+}
 
-	if sourcePth == "" {
-		usage()
-		os.Exit(1)
+// newFlagSet generates a subcommand flag set with usage information, printed to stderr.
+func newFlagSet(name, operands string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %v %v [OPTION] %v\n", programName(), name, operands)
+		fs.PrintDefaults()
 	}
+	return fs
+}
 
-	check := *flagCheck
-	getFrames := *flagGetFrames
-	trimEdges := *flagTrimEdges
+// parseFlags parses command line flags,
+// reporting malformed flags as usage errors, already described by the flag set.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
 
-	if trimEdges < 0 {
-		fmt.Fprintln(os.Stderr, "trim edges cannot be negative")
-		os.Exit(1)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return errUsage
 	}
 
-	stitchString := *flagStitch
-	stitchP, ok := buttery.ParseStitch(stitchString)
+	return err
+}
 
-	if !ok {
-		usage()
-		os.Exit(1)
+// parseSingleInput parses subcommand flags, requiring exactly one input path.
+func parseSingleInput(fs *flag.FlagSet, args []string) (string, error) {
+	if err := parseFlags(fs, args); err != nil {
+		return "", err
 	}
 
-	fadeColorString := *flagFadeColor
-	fadeColorUint32, err := strconv.ParseUint(fadeColorString, 0, 32)
+	rest := fs.Args()
 
-	if err != nil {
-		usage()
-		os.Exit(1)
+	if len(rest) != 1 || rest[0] == "" {
+		fs.Usage()
+		return "", errUsage
 	}
 
-	if fadeColorUint32 > 0xFFFFFF {
-		usage()
-		os.Exit(1)
-	}
+	return rest[0], nil
+}
 
-	fadeColorRGBA := color.RGBA{
-		R: uint8((fadeColorUint32 >> 16) & 0xFF),
-		G: uint8((fadeColorUint32 >> 8) & 0xFF),
-		B: uint8(fadeColorUint32 & 0xFF),
-		A: 0x00,
-	}
+// errUsage reports malformed command lines, after printing usage information.
+var errUsage = errors.New("usage")
 
-	config := buttery.NewConfig()
-	config.Transparent = *flagTransparent
-	config.TrimStart = *flagTrimStart + trimEdges
-	config.TrimEnd = *flagTrimEnd + trimEdges
-	config.CutInterval = *flagCutInterval
-	config.Window = *flagWindow
-	config.Shift = *flagShift
-	config.Stitch = *stitchP
-	config.FadeColor = fadeColorRGBA
-	config.FadeRate = *flagFadeRate
-	config.ScaleDelay = *flagScaleDelay
-	config.PanVelocity = *flagPanVelocity
-	config.LoopCount = *flagLoopCount
-
-	if err2 := config.Validate(); err2 != nil {
-		fmt.Fprintln(os.Stderr, err2)
-		os.Exit(1)
-	}
+// exitStatus requests a particular nonzero exit status, after printing any report.
+type exitStatus int
 
-	sourceFile, err := os.Open(sourcePth)
+// Error describes the exit status.
+func (o exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(o))
+}
 
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
-	}
+// legacyFlagSet registers the original, subcommand free flags.
+func legacyFlagSet(ef *editFlags, bf *batchFlags, check, getFrames, version, help *bool) *flag.FlagSet {
+	fs := flag.NewFlagSet("buttery", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usageText())
+	}
+	fs.BoolVar(check, "check", false, "validate basic GIF format file integrity (see the check command for deeper linting)")
+	fs.BoolVar(getFrames, "getFrames", false, "query total input GIF frame count (alias for the frames command)")
+	ef.register(fs)
+	ef.registerOutput(fs)
+	bf.register(fs)
+	fs.BoolVar(version, "version", false, "show version information")
+	fs.BoolVar(help, "help", false, "show usage information")
+	return fs
+}
 
-	sourceGif, err := gif.DecodeAll(sourceFile)
+// runLegacy dispatches the original, subcommand free command line.
+func runLegacy(args []string) error {
+	var ef editFlags
+	var bf batchFlags
+	var check, getFrames, version, help bool
+	fs := legacyFlagSet(&ef, &bf, &check, &getFrames, &version, &help)
 
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	if err := parseFlags(fs, args); err != nil {
+		return err
 	}
 
-	if check {
-		os.Exit(0)
+	if help {
+		fmt.Print(usageText())
+		return nil
 	}
 
-	sourcePaletteds := sourceGif.Image
+	if version {
+		fmt.Println(buttery.Version)
+		return nil
+	}
+
+	rest := fs.Args()
 
-	if getFrames {
-		fmt.Println(len(sourcePaletteds))
-		os.Exit(0)
+	if len(rest) == 0 || slices.Contains(rest, "") || ((check || getFrames) && len(rest) != 1) {
+		fmt.Fprint(os.Stderr, usageText())
+		return errUsage
 	}
 
-	sourceBasename := strings.TrimSuffix(sourcePth, filepath.Ext(sourcePth))
-	destPth := fmt.Sprintf("%v.buttery.gif", sourceBasename)
+	switch {
+	case check:
+		return checkFile(rest[0])
+	case getFrames:
+		return countFrames(rest[0])
+	default:
+		return editAll(&ef, &bf, rest)
+	}
+}
 
-	if err := config.Edit(destPth, sourceGif); err != nil {
+// run dispatches a command line, returning the exit status.
+func run(args []string) int {
+	var err error
+
+	if len(args) > 0 && args[0] == "help" {
+		if len(args) > 1 {
+			if c, ok := commands[args[1]]; ok {
+				err = c.run([]string{"-help"})
+			}
+		} else {
+			fmt.Print(usageText())
+		}
+	} else if len(args) > 0 {
+		if c, ok := commands[args[0]]; ok {
+			err = c.run(args[1:])
+		} else {
+			err = runLegacy(args)
+		}
+	} else {
+		fmt.Fprint(os.Stderr, usageText())
+		err = errUsage
+	}
+
+	var status exitStatus
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 1
+	case errors.As(err, &status):
+		return int(status)
+	default:
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		return 1
 	}
 }
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
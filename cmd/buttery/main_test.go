@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+// encodeTestGIF generates a small animation with the given frame count.
+func encodeTestGIF(t *testing.T, frames int) []byte {
+	t.Helper()
+	palette := color.Palette{color.Black, color.White}
+	g := &gif.GIF{}
+
+	for i := 0; i < frames; i++ {
+		paletted := image.NewPaletted(image.Rect(0, 0, 4, 3), palette)
+		paletted.SetColorIndex(i%4, i%3, 1)
+		g.Image = append(g.Image, paletted)
+		g.Delay = append(g.Delay, 4)
+	}
+
+	var buf bytes.Buffer
+
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// writeTestFile writes a file, creating parent directories as needed.
+func writeTestFile(t *testing.T, pth string, data []byte) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(pth), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(pth, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runMain runs the command line dispatcher with the given stdin,
+// capturing the exit status, stdout, and stderr.
+func runMain(t *testing.T, stdin []byte, args ...string) (int, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	stdinPth := filepath.Join(dir, "stdin")
+	writeTestFile(t, stdinPth, stdin)
+	var files [3]*os.File
+
+	for i, name := range []string{"stdin", "stdout", "stderr"} {
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_RDWR|os.O_CREATE, 0644)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer func() {
+			if err := f.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		files[i] = f
+	}
+
+	stdinFile, stdoutFile, stderrFile := os.Stdin, os.Stdout, os.Stderr
+	os.Stdin, os.Stdout, os.Stderr = files[0], files[1], files[2]
+	status := run(args)
+	os.Stdin, os.Stdout, os.Stderr = stdinFile, stdoutFile, stderrFile
+	var outputs [2]string
+
+	for i, name := range []string{"stdout", "stderr"} {
+		output, err := os.ReadFile(filepath.Join(dir, name))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		outputs[i] = string(output)
+	}
+
+	return status, outputs[0], outputs[1]
+}
+
+func TestRunDispatch(t *testing.T) {
+	dir := t.TempDir()
+	pth := filepath.Join(dir, "input.gif")
+	corruptPth := filepath.Join(dir, "corrupt.gif")
+	writeTestFile(t, pth, encodeTestGIF(t, 3))
+	writeTestFile(t, corruptPth, []byte("GIF89a"))
+
+	for _, tc := range []struct {
+		args   []string
+		status int
+		stdout string
+		stderr string
+	}{
+		{status: 1, stderr: "Usage:"},
+		{args: []string{"help"}, stdout: "Commands:"},
+		{args: []string{"help", "frames"}, stderr: "frames export [OPTION]"},
+		{args: []string{"-help"}, stdout: "Legacy options"},
+		{args: []string{"-version"}, stdout: buttery.Version + "\n"},
+		{args: []string{"-bogus"}, status: 1, stderr: "flag provided but not defined"},
+		{args: []string{"frames", pth}, stdout: "3\n"},
+		{args: []string{"-getFrames", pth}, stdout: "3\n"},
+		{args: []string{"-getFrames", pth, pth}, status: 1, stderr: "Usage:"},
+		{args: []string{"frames", corruptPth}, status: 1, stderr: "unexpected EOF"},
+		{args: []string{"check", pth}},
+		{args: []string{"check", corruptPth}, status: 3, stdout: "decode"},
+		{args: []string{"-check", pth}},
+		{args: []string{"-check", corruptPth}, status: 1, stderr: "unexpected EOF"},
+		{args: []string{"info", pth}, stdout: "frames"},
+	} {
+		status, stdout, stderr := runMain(t, nil, tc.args...)
+
+		if status != tc.status {
+			t.Errorf("%v: expected exit status %d, got %d (stderr %q)", tc.args, tc.status, status, stderr)
+		}
+
+		if !strings.Contains(stdout, tc.stdout) || (tc.stdout == "" && stdout != "") {
+			t.Errorf("%v: expected stdout containing %q, got %q", tc.args, tc.stdout, stdout)
+		}
+
+		if !strings.Contains(stderr, tc.stderr) || (tc.stderr == "" && stderr != "") {
+			t.Errorf("%v: expected stderr containing %q, got %q", tc.args, tc.stderr, stderr)
+		}
+	}
+}
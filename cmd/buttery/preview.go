@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// runPreview executes the preview command.
+func runPreview(args []string) (err error) {
+	var ef editFlags
+	fs := newFlagSet("preview", "<input.gif>")
+	ef.register(fs)
+	sourcePth, err := parseSingleInput(fs, args)
+
+	if err != nil {
+		return err
+	}
+
+	recipe, pipeline, err := ef.config()
+
+	if err != nil {
+		return err
+	}
+
+	if ef.plan {
+		return ef.printPlan(recipe, pipeline, sourcePth)
+	}
+
+	sourceFile, err := openInput(sourcePth)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, sourceFile.Close())
+	}()
+
+	src, err := ef.decode(recipe, sourceFile)
+
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := ef.context()
+	defer cancel()
+	edited, err := src.edit(ctx, pipeline, src.format)
+
+	if ef.progress {
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var delay int
+
+	for _, d := range edited.delays() {
+		delay += d
+	}
+
+	fmt.Printf("operations: %v\n", pipeline.Operations)
+	fmt.Printf("frames: %d -> %d\n", src.frames(), edited.frames())
+	fmt.Printf("duration: %v\n", centiseconds(delay))
+	return nil
+}
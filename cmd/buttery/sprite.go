@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mcandre/buttery"
+)
+
+// spriteSubcommands indexes the sprite subcommands by name.
+var spriteSubcommands = map[string]func(args []string) error{
+	"export": runSpriteExport,
+	"import": runSpriteImport,
+}
+
+// runSprite executes the sprite command.
+func runSprite(args []string) error {
+	if len(args) > 0 {
+		if run, ok := spriteSubcommands[args[0]]; ok {
+			return run(args[1:])
+		}
+	}
+
+	program := programName()
+	fmt.Fprintf(os.Stderr, "Usage: %v sprite export [OPTION] <input>\n", program)
+	fmt.Fprintf(os.Stderr, "       %v sprite import [OPTION] <sheet.png>\n", program)
+	return errUsage
+}
+
+// runSpriteExport executes the sprite export command,
+// tiling the edited frames into a sprite sheet.
+func runSpriteExport(args []string) (err error) {
+	var ef editFlags
+	var columns int
+	var css bool
+	fs := newFlagSet("sprite export", "<input>")
+	ef.register(fs)
+	fs.StringVar(&ef.out, "o", "", "output sheet PNG (default: <input>.sheet.png)")
+	fs.BoolVar(&ef.force, "force", false, "overwrite existing output files")
+	fs.IntVar(&columns, "columns", 0, "grid columns (default: roughly square)")
+	fs.BoolVar(&css, "css", false, "also write a <sheet>.css steps() animation and a <sheet>.html preview")
+	sourcePth, err := parseSingleInput(fs, args)
+
+	if err != nil {
+		return err
+	}
+
+	destPth := ef.out
+
+	if destPth == "" {
+		if sourcePth == stdio {
+			return errors.New("-o is required for stdin input")
+		}
+
+		destPth = strings.TrimSuffix(sourcePth, filepath.Ext(sourcePth)) + ".sheet.png"
+	}
+
+	if destPth == stdio {
+		return errors.New("sprite sheets require a named output file")
+	}
+
+	destPths := []string{destPth, buttery.SpriteSidecar(destPth, ".json")}
+
+	if css {
+		destPths = append(destPths, buttery.SpriteSidecar(destPth, ".css"), buttery.SpriteSidecar(destPth, ".html"))
+	}
+
+	for _, pth := range destPths {
+		if err2 := ef.checkOutput(pth); err2 != nil {
+			return err2
+		}
+	}
+
+	recipe, pipeline, err := ef.config()
+
+	if err != nil {
+		return err
+	}
+
+	sourceFile, err := openInput(sourcePth)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, sourceFile.Close())
+	}()
+
+	src, err := ef.decode(recipe, sourceFile)
+
+	if err != nil {
+		return err
+	}
+
+	sourceAnimation, err := src.asAnimation()
+
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := ef.context()
+	defer cancel()
+	edited, err := pipeline.EditAnimationContext(ctx, sourceAnimation)
+
+	if ef.progress {
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err2 := os.MkdirAll(filepath.Dir(destPth), 0755); err2 != nil {
+		return err2
+	}
+
+	sheet, err := buttery.ExportSpriteSheet(destPth, edited, columns)
+
+	if err != nil || !css {
+		return err
+	}
+
+	return buttery.ExportSpriteCSS(destPth, sheet)
+}
+
+// runSpriteImport executes the sprite import command,
+// slicing a sprite sheet into frames and applying any edit options.
+func runSpriteImport(args []string) (err error) {
+	var ef editFlags
+	var columns, rows, frames, delay int
+	fs := newFlagSet("sprite import", "<sheet.png>")
+
+	if err2 := ef.registerImport(fs); err2 != nil {
+		return err2
+	}
+
+	fs.IntVar(&columns, "columns", 0, "grid columns, for sheets without <sheet>.json metadata")
+	fs.IntVar(&rows, "rows", 0, "grid rows, for sheets without <sheet>.json metadata")
+	fs.IntVar(&frames, "frames", 0, "grid frame count (default: every cell)")
+	fs.IntVar(&delay, "delay", buttery.DefaultImportDelay, "grid frame delay in centisec")
+	sheetPth, err := parseSingleInput(fs, args)
+
+	if err != nil {
+		return err
+	}
+
+	if columns == 0 && rows == 0 {
+		if sheetPth == stdio {
+			return errors.New("-columns and -rows are required for stdin input")
+		}
+
+		sourceAnimation, err2 := buttery.ImportSpriteSheet(sheetPth)
+
+		if err2 != nil {
+			return err2
+		}
+
+		return ef.editImport(sheetPth, sourceAnimation)
+	}
+
+	sheetFile, err := openInput(sheetPth)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = errors.Join(err, sheetFile.Close())
+	}()
+
+	sheetImage, _, err := image.Decode(sheetFile)
+
+	if err != nil {
+		return err
+	}
+
+	bounds := sheetImage.Bounds()
+	sheet, err := buttery.NewSpriteGrid(bounds.Dx(), bounds.Dy(), columns, rows, frames, delay)
+
+	if err != nil {
+		return err
+	}
+
+	sourceAnimation, err := sheet.Slice(sheetImage)
+
+	if err != nil {
+		return err
+	}
+
+	return ef.editImport(sheetPth, sourceAnimation)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// fileState models the change detection fields of a watched file.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	err     string
+}
+
+// statFiles snapshots the state of each file.
+//
+// Missing files, such as those mid save, are part of the state.
+func statFiles(pths []string) []fileState {
+	states := make([]fileState, len(pths))
+
+	for i, pth := range pths {
+		info, err := os.Stat(pth)
+
+		if err != nil {
+			states[i].err = err.Error()
+			continue
+		}
+
+		states[i].modTime, states[i].size = info.ModTime(), info.Size()
+	}
+
+	return states
+}
+
+// watchEdit regenerates an output whenever the input or recipe changes,
+// polling modification times until interrupted.
+//
+// Watching implies -force.
+// Errors are reported on stderr without exiting.
+func (o *editFlags) watchEdit(sourcePth string) error {
+	if sourcePth == stdio {
+		return errors.New("-watch requires a named input file")
+	}
+
+	if o.watchInterval <= 0 {
+		return errors.New("-watchInterval must be positive")
+	}
+
+	pths := []string{sourcePth}
+
+	if o.recipe != "" {
+		pths = append(pths, o.recipe)
+	}
+
+	// Every regeneration replaces the previous output.
+	o.force = true
+	var previous []fileState
+
+	for {
+		states := statFiles(pths)
+
+		if !equalStates(states, previous) {
+			previous = states
+			timestamp := time.Now().Format(time.TimeOnly)
+
+			if err := o.edit(sourcePth); err != nil {
+				fmt.Fprintf(os.Stderr, "%v %v: %v\n", timestamp, sourcePth, err)
+			} else {
+				fmt.Fprintf(os.Stderr, "%v %v: updated\n", timestamp, sourcePth)
+			}
+		}
+
+		time.Sleep(o.watchInterval)
+	}
+}
+
+// equalStates reports whether two snapshots match.
+func equalStates(a, b []fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size || a[i].err != b[i].err {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStatFiles(t *testing.T) {
+	dir := t.TempDir()
+	pth := filepath.Join(dir, "input.gif")
+	missing := filepath.Join(dir, "missing.toml")
+
+	if err := os.WriteFile(pth, []byte("GIF89a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	states := statFiles([]string{pth, missing})
+
+	if states[0].size != 6 || states[0].err != "" {
+		t.Errorf("expected a 6 byte file, got %+v", states[0])
+	}
+
+	if states[1].err == "" {
+		t.Errorf("expected the missing file to report an error")
+	}
+
+	if !equalStates(states, statFiles([]string{pth, missing})) {
+		t.Errorf("expected unchanged files to match")
+	}
+
+	if err := os.WriteFile(pth, []byte("GIF89a!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if equalStates(states, statFiles([]string{pth, missing})) {
+		t.Errorf("expected a resized file to differ")
+	}
+
+	states = statFiles([]string{pth, missing})
+	later := time.Now().Add(time.Hour)
+
+	if err := os.Chtimes(pth, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if equalStates(states, statFiles([]string{pth, missing})) {
+		t.Errorf("expected a touched file to differ")
+	}
+}
+
+func TestEqualStatesLength(t *testing.T) {
+	if equalStates([]fileState{{size: 1}}, nil) {
+		t.Errorf("expected snapshots of different lengths to differ")
+	}
+
+	if !equalStates(nil, nil) {
+		t.Errorf("expected empty snapshots to match")
+	}
+}
@@ -1,18 +1,12 @@
 package buttery
 
 import (
+	"context"
 	"errors"
-	"image"
-	"image/color"
-	"image/draw"
+	"fmt"
 	"image/gif"
+	"io"
 	"math"
-	"math/rand"
-	"os"
-	"slices"
-
-	"github.com/andybons/gogif"
-	"github.com/anthonynsimon/bild/transform"
 )
 
 // Config models a set of animation editing manipulations.
@@ -41,14 +35,16 @@ type Config struct {
 	Shift int
 
 	// Stitch denotes a loop continuity transition (Default Mirror).
+	//
+	// Stitch specific knobs, such as Fade color and rate, live in Stitch.Params.
 	Stitch Stitch
 
-	// FadeColor denotes a hue for fade transitions (Default opaque black).
-	// Alpha channel ignored.
-	FadeColor color.RGBA
-
-	// FadeRate denotes the speed of fade transitions (Default 1.0).
-	FadeRate float64
+	// Fade denotes a fade following every Stitch except Shuffle and Fade,
+	// applied after the Shift (Default Fade, towards black at rate 1).
+	//
+	// The Fade stitch applies its own parameters instead.
+	// None, or an empty Name, disables the trailing fade.
+	Fade Stitch
 
 	// ScaleDelay multiplies each frame delay by a factor (Default 1.0).
 	//
@@ -57,310 +53,261 @@ type Config struct {
 	// A negative scale delay reverses the incoming sequence.
 	ScaleDelay float64
 
-	// PanVelocity specifies the number of pixels to shift the canvas per frame (Default: 1.0).
-	PanVelocity float64
-
 	// LoopCount denotes how many times to play the animation (Default 0).
 	//
 	// -1 indicates one play.
 	// 0 indicates infinite, endless plays.
 	// N indicates 1+N iterations.
 	LoopCount int
+
+	// Workers denotes how many frames to process concurrently (Default zero).
+	//
+	// Zero indicates one worker per CPU.
+	// Output is identical regardless of worker count.
+	Workers int
+
+	// MemoryLimit caps the bytes of frames held in memory while streaming (Default zero).
+	//
+	// Zero indicates no streaming.
+	// See Pipeline.MemoryLimit.
+	MemoryLimit int64
 }
 
 // NewConfig generates a default Config.
 func NewConfig() Config {
 	return Config{
 		Stitch:     Mirror,
+		Fade:       Fade,
 		ScaleDelay: 1.0,
 	}
 }
 
-// Validate checks for basic Config integrity.
+// Validate checks for basic Config integrity,
+// reporting every problem found.
 func (o *Config) Validate() error {
+	var errs []error
+
 	if o.TrimEdges < 0 {
-		return errors.New("trim edges cannot be negative")
+		errs = append(errs, fmt.Errorf("trim edges %w", ErrNegative))
 	}
 
 	if o.TrimStart < 0 {
-		return errors.New("trim start cannot be negative")
+		errs = append(errs, fmt.Errorf("trim start %w", ErrNegative))
 	}
 
 	if o.TrimEnd < 0 {
-		return errors.New("trim end cannot be negative")
+		errs = append(errs, fmt.Errorf("trim end %w", ErrNegative))
 	}
 
 	if o.CutInterval < 0 || o.CutInterval == 1 {
-		return errors.New("cut interval cannot be less than two")
+		errs = append(errs, ErrCutIntervalTooShort)
 	}
 
 	if o.Window < 0 {
-		return errors.New("window cannot be negative")
+		errs = append(errs, fmt.Errorf("window %w", ErrNegative))
 	}
 
-	return o.Stitch.Validate()
-}
-
-// Edit applies the configured GIF manipulations.
-func (o *Config) Edit(destPth string, sourceGif *gif.GIF) error {
-	sourcePaletteds := sourceGif.Image
-	sourcePalettedsLen := len(sourcePaletteds)
+	if o.MemoryLimit < 0 {
+		errs = append(errs, fmt.Errorf("memory limit %w", ErrNegative))
+	}
 
-	if o.TrimStart+o.TrimEnd >= sourcePalettedsLen {
-		return errors.New("minimum 1 output frame")
+	if math.IsNaN(o.ScaleDelay) || math.IsInf(o.ScaleDelay, 0) {
+		errs = append(errs, fmt.Errorf("scale delay %w", ErrNotFinite))
 	}
 
-	var reverse bool
-	scaleDelay := o.ScaleDelay
+	if err := o.Stitch.Validate(); err != nil {
+		errs = append(errs, err)
+	}
 
-	if scaleDelay < 0 {
-		reverse = true
-		scaleDelay *= -1.0
+	if o.Fade.Name != "" {
+		if err := o.Fade.Validate(); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	window := o.Window
+	return errors.Join(errs...)
+}
+
+// ValidateFor checks the Config against the frames of a source GIF,
+// reporting every problem found.
+func (o *Config) ValidateFor(sourceGif *gif.GIF) error {
+	var errs []error
 
-	if window > sourcePalettedsLen-o.TrimStart-o.TrimEnd {
-		return errors.New("window longer than subsequence")
+	if err := o.Validate(); err != nil {
+		errs = append(errs, err)
 	}
 
-	sourceDelays := sourceGif.Delay
-	sourceWidth, sourceHeight := GetDimensions(sourcePaletteds)
-	canvasImage := image.NewRGBA(image.Rect(0, 0, sourceWidth, sourceHeight))
-	canvasBounds := canvasImage.Bounds()
-	paletteSize := GetPaletteSize(sourcePaletteds)
-	clonePaletteds := make([]*image.Paletted, sourcePalettedsLen)
-	quantizer := gogif.MedianCutQuantizer{NumColor: paletteSize}
-	var disposals []byte
-	c := color.Alpha16{0}
-
-	if o.Transparent {
-		c = color.Transparent
+	if err := validateSource(sourceGif); err != nil {
+		return errors.Join(append(errs, err)...)
 	}
 
-	draw.Src.Draw(canvasImage, canvasBounds, &image.Uniform{sourcePaletteds[0].Palette.Convert(c)}, image.Point{})
+	return errors.Join(append(errs, o.validateFrameCount(len(sourceGif.Image)))...)
+}
 
-	for i, sourcePaletted := range sourcePaletteds {
-		im := canvasImage
+// ValidateForAnimation checks the Config against the frames of a source animation,
+// reporting every problem found.
+func (o *Config) ValidateForAnimation(sourceAnimation *Animation) error {
+	var errs []error
 
-		if o.Transparent {
-			im = image.NewRGBA(image.Rect(0, 0, sourceWidth, sourceHeight))
-		}
+	if err := o.Validate(); err != nil {
+		errs = append(errs, err)
+	}
 
-		draw.Over.Draw(im, canvasBounds, sourcePaletted, image.Point{})
-		clonePaletted := image.NewPaletted(canvasBounds, sourcePaletted.Palette)
-		quantizer.Quantize(clonePaletted, canvasBounds, im, image.Point{})
-		clonePaletteds[i] = clonePaletted
-		disposal := byte(gif.DisposalNone)
+	if len(sourceAnimation.Timeline) == 0 {
+		return errors.Join(append(errs, ErrNoFrames)...)
+	}
 
-		if o.Transparent {
-			disposal = byte(gif.DisposalBackground)
-		}
+	return errors.Join(append(errs, o.validateFrameCount(len(sourceAnimation.Timeline)))...)
+}
 
-		disposals = append(disposals, disposal)
+// validateFrameCount checks trims and window against a source frame count.
+func (o *Config) validateFrameCount(frames int) error {
+	if o.TrimEdges < 0 || o.TrimStart < 0 || o.TrimEnd < 0 {
+		return nil
 	}
 
-	if reverse && o.Stitch != Shuffle {
-		slices.Reverse(clonePaletteds)
-		slices.Reverse(sourceDelays)
+	trimStart, trimEnd := o.trims()
+
+	// Negative sums indicate overflow.
+	if trimStart < 0 || trimEnd < 0 || trimStart >= frames || trimEnd >= frames-trimStart {
+		return fmt.Errorf("%w: trims remove all %d frames", ErrTooFewFrames, frames)
 	}
 
-	clonePaletteds = clonePaletteds[o.TrimStart:]
-	clonePaletteds = clonePaletteds[:len(clonePaletteds)-o.TrimEnd]
-	sourceDelays = sourceDelays[o.TrimStart:]
-	sourceDelays = sourceDelays[:len(sourceDelays)-o.TrimEnd]
-	cloneDisposals := disposals[o.TrimStart:]
-	cloneDisposals = cloneDisposals[:len(clonePaletteds)-o.TrimEnd]
-
-	if window != 0 {
-		clonePaletteds = clonePaletteds[:window]
-		sourceDelays = sourceDelays[:window]
-		cloneDisposals = cloneDisposals[:window]
+	if frames -= trimStart + trimEnd; o.Window > frames {
+		return fmt.Errorf("%w: window %d exceeds %d frames", ErrWindowTooLong, o.Window, frames)
 	}
 
-	clonePalettedsLen := len(clonePaletteds)
+	return nil
+}
 
-	if o.CutInterval != 0 {
-		var reducedPaletteds []*image.Paletted
-		var reducedDelays []int
-		var reducedDisposals []byte
-
-		for i := 0; i < clonePalettedsLen; i++ {
-			if (1+i)%o.CutInterval != 0 {
-				reducedPaletteds = append(reducedPaletteds, clonePaletteds[i])
-				reducedDelays = append(reducedDelays, sourceDelays[i])
-				reducedDisposals = append(reducedDisposals, cloneDisposals[i])
-			}
-		}
+// trims sums TrimEdges into the start and end trims.
+func (o *Config) trims() (int, int) {
+	return o.TrimEdges + o.TrimStart, o.TrimEdges + o.TrimEnd
+}
 
-		clonePaletteds = reducedPaletteds
-		clonePalettedsLen = len(reducedPaletteds)
-		sourceDelays = reducedDelays
-		cloneDisposals = reducedDisposals
+// Edit applies the configured GIF manipulations,
+// writing the result to the given file path.
+//
+// The file is replaced atomically, see WriteFileAtomic.
+func (o *Config) Edit(destPth string, sourceGif *gif.GIF) error {
+	butteryGif, err := o.EditGIF(sourceGif)
+	if err != nil {
+		return err
 	}
 
-	var butteryPalettedsLen int
+	return WriteFileAtomic(destPth, func(w io.Writer) error {
+		return gif.EncodeAll(w, butteryGif)
+	})
+}
 
-	switch o.Stitch {
-	case Mirror:
-		butteryPalettedsLen = 2*clonePalettedsLen - 1
-	case FlipH:
-		butteryPalettedsLen = 2 * clonePalettedsLen
-	case FlipV:
-		butteryPalettedsLen = 2 * clonePalettedsLen
-	default:
-		butteryPalettedsLen = clonePalettedsLen
+// EditTo decodes a GIF from the given reader,
+// applies the configured GIF manipulations,
+// and encodes the result to the given writer.
+func (o *Config) EditTo(w io.Writer, r io.Reader) error {
+	return o.EditToContext(context.Background(), w, r)
+}
+
+// EditToContext decodes a GIF from the given reader,
+// applies the configured GIF manipulations,
+// and encodes the result to the given writer,
+// reporting progress and honoring cancellation.
+//
+// A positive MemoryLimit streams frames,
+// deferring frame count checks to the individual operations.
+func (o *Config) EditToContext(ctx context.Context, w io.Writer, r io.Reader) error {
+	if o.MemoryLimit > 0 {
+		if err := o.Validate(); err != nil {
+			return err
+		}
+
+		return o.Pipeline().EditToContext(ctx, w, r)
 	}
 
-	butteryPaletteds := make([]*image.Paletted, butteryPalettedsLen)
-	butteryDelays := make([]int, butteryPalettedsLen)
-	butteryDelaysLen := butteryPalettedsLen
-	butteryDisposals := make([]byte, butteryPalettedsLen)
-	var r int
+	sourceGif, err := gif.DecodeAll(r)
+	if err != nil {
+		return err
+	}
 
-	for i := 0; i < butteryPalettedsLen; i++ {
-		paletted := clonePaletteds[r]
+	butteryGif, err := o.EditGIFContext(ctx, sourceGif)
+	if err != nil {
+		return err
+	}
 
-		if (o.Stitch == FlipH || o.Stitch == FlipV) && i > clonePalettedsLen-1 {
-			flipPaletted := image.NewPaletted(canvasBounds, nil)
+	return gif.EncodeAll(w, butteryGif)
+}
 
-			var flippedRGBA *image.RGBA
+// EditGIF applies the configured GIF manipulations in memory.
+//
+// The source GIF is left unmodified.
+func (o *Config) EditGIF(sourceGif *gif.GIF) (*gif.GIF, error) {
+	return o.EditGIFContext(context.Background(), sourceGif)
+}
 
-			if o.Stitch == FlipH {
-				flippedRGBA = transform.FlipH(paletted)
-			} else {
-				flippedRGBA = transform.FlipV(paletted)
-			}
+// EditGIFContext applies the configured GIF manipulations in memory,
+// reporting progress and honoring cancellation.
+//
+// The source GIF is left unmodified.
+func (o *Config) EditGIFContext(ctx context.Context, sourceGif *gif.GIF) (*gif.GIF, error) {
+	if err := o.ValidateFor(sourceGif); err != nil {
+		return nil, err
+	}
 
-			quantizer.Quantize(flipPaletted, canvasBounds, flippedRGBA, image.Point{})
-			paletted = flipPaletted
-		}
+	return o.Pipeline().EditGIFContext(ctx, sourceGif)
+}
 
-		panVelocity := int(float64(i) * o.PanVelocity)
+// fades reports whether the trailing Fade applies.
+func (o *Config) fades() bool {
+	return o.Fade.Name != "" && !o.Fade.Is(None.Name) && !o.Stitch.Is(Shuffle.Name) && !o.Stitch.Is(Fade.Name)
+}
 
-		if o.Stitch == PanH {
-			panPaletted := pan(paletted, panVelocity, 0)
-			paletted = panPaletted
-		}
+// Pipeline generates the ordered editing stages modeled by the Config.
+func (o *Config) Pipeline() Pipeline {
+	var operations []Operation
+	scaleDelay := o.ScaleDelay
 
-		if o.Stitch == PanV {
-			panPaletted := pan(paletted, 0, panVelocity)
-			paletted = panPaletted
-		}
+	if scaleDelay < 0 {
+		scaleDelay *= -1.0
 
-		butteryPaletteds[i] = paletted
-		sourceDelay := sourceDelays[r]
-		butteryDelays[i] = int(math.Max(2.0, scaleDelay*float64(sourceDelay)))
-		butteryDisposals[i] = cloneDisposals[r]
-
-		switch {
-		case o.Stitch == Mirror && i >= clonePalettedsLen-1:
-			r--
-		case (o.Stitch == FlipH || o.Stitch == FlipV) && i == clonePalettedsLen-1:
-			r = 0
-		default:
-			r++
+		if !o.Stitch.Is(Shuffle.Name) {
+			operations = append(operations, Reverse{})
 		}
 	}
 
-	if o.Stitch == Shuffle {
-		rand.Shuffle(butteryPalettedsLen, func(i, j int) {
-			butteryPaletteds[i], butteryPaletteds[j] = butteryPaletteds[j], butteryPaletteds[i]
-		})
-		rand.Shuffle(butteryDelaysLen, func(i, j int) {
-			butteryDelays[i], butteryDelays[j] = butteryDelays[j], butteryDelays[i]
-		})
-	} else {
-		shiftedPaletteds := make([]*image.Paletted, butteryPalettedsLen)
-		shiftedDelays := make([]int, butteryDelaysLen)
-		shiftedDisposals := make([]byte, butteryDelaysLen)
-		target := o.FadeColor
-		targetR, targetG, targetB := float64(target.R), float64(target.G), float64(target.B)
-		s := float64(butteryPalettedsLen) - 1.0
-
-		for i := range butteryPaletteds {
-			r = signedMod(i+o.Shift, butteryPalettedsLen)
-			shiftedPaletteds[i] = butteryPaletteds[r]
-			shiftedDelays[i] = butteryDelays[r]
-			shiftedDisposals[i] = butteryDisposals[r]
-
-			fadedPaletted := shiftedPaletteds[i]
-			fade := float64(s) / float64(butteryPalettedsLen-1)
-			palette := fadedPaletted.Palette
-			fadedPalette := make(color.Palette, len(palette))
-
-			for j, c := range palette {
-				r, g, b, a := c.RGBA()
-				rF, gF, bF := float64(r>>8), float64(g>>8), float64(b>>8)
-				rF = rF + (targetR-rF)*fade
-				rF = max(rF, 0.0)
-				rF = min(rF, 255.0)
-				gF = gF + (targetG-gF)*fade
-				gF = max(gF, 0.0)
-				gF = min(gF, 255.0)
-				bF = bF + (targetB-bF)*fade
-				bF = max(bF, 0.0)
-				bF = min(bF, 255.0)
-				r2, g2, b2, a2 := uint8(rF), uint8(gF), uint8(bF), uint8(a>>8)
-				fadedPalette[j] = color.RGBA{R: r2, G: g2, B: b2, A: a2}
-			}
-
-			fadedPaletted.Palette = fadedPalette
-			shiftedPaletteds[i] = fadedPaletted
-
-			if i < butteryPalettedsLen/2 {
-				s -= o.FadeRate
-			} else if i > butteryPalettedsLen/2 {
-				s += o.FadeRate
-			}
-
-			s = min(s, float64(butteryPalettedsLen)-1.0)
-			s = max(s, 0.0)
-		}
-
-		butteryPaletteds = shiftedPaletteds
-		butteryDelays = shiftedDelays
-		butteryDisposals = shiftedDisposals
+	if trimStart, trimEnd := o.trims(); trimStart != 0 || trimEnd != 0 {
+		operations = append(operations, Trim{Start: trimStart, End: trimEnd})
 	}
 
-	butteryGif := gif.GIF{
-		LoopCount:       o.LoopCount,
-		BackgroundIndex: sourceGif.BackgroundIndex,
-		Config:          sourceGif.Config,
-		Image:           butteryPaletteds,
-		Delay:           butteryDelays,
-		Disposal:        butteryDisposals,
+	if o.Window != 0 {
+		operations = append(operations, Window{Length: o.Window})
 	}
 
-	butteryFile, err := os.Create(destPth)
-	if err != nil {
-		return err
+	if o.CutInterval != 0 {
+		operations = append(operations, Cut{Interval: o.CutInterval})
 	}
 
-	return gif.EncodeAll(butteryFile, &butteryGif)
-}
-
-// pan offsets an image by the given horizontal and vertical offsets.
-func pan(img *image.Paletted, dx, dy int) *image.Paletted {
-	bounds := img.Bounds()
-	panned := image.NewPaletted(bounds, img.Palette)
+	stitch := StitchStage{Stitch: o.Stitch}
 
-	for x := bounds.Min.X; x < bounds.Max.X; x++ {
-		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-			srcX, srcY := signedMod(x, bounds.Max.X), signedMod(y, bounds.Max.Y)
-			dstX, dstY := signedMod(x+dx, bounds.Max.X), signedMod(y+dy, bounds.Max.Y)
+	switch {
+	case o.Stitch.Is(Shuffle.Name) || o.Shift == 0:
+		operations = append(operations, stitch)
+	case o.Stitch.Is(Fade.Name):
+		// Fade gradients apply to the final output positions.
+		operations = append(operations, Shift{Offset: o.Shift}, stitch)
+	default:
+		operations = append(operations, stitch, Shift{Offset: o.Shift})
+	}
 
-			panned.SetColorIndex(dstX, dstY, img.ColorIndexAt(srcX, srcY))
-		}
+	if o.fades() {
+		operations = append(operations, StitchStage{Stitch: o.Fade})
 	}
 
-	return panned
-}
+	operations = append(operations, ScaleDelay{Factor: scaleDelay})
 
-// signedMod reverses direction for negative n denominators.
-//
-// Warning: Each programming language may implements subtly distinct modulo algorithms.
-// https://en.wikipedia.org/wiki/Modulo
-func signedMod(a, n int) int {
-	return a - (n * int(math.Floor(float64(a)/float64(n))))
+	return Pipeline{
+		Transparent: o.Transparent,
+		LoopCount:   o.LoopCount,
+		Operations:  operations,
+		Workers:     o.Workers,
+		MemoryLimit: o.MemoryLimit,
+	}
 }
@@ -0,0 +1,56 @@
+package buttery
+
+import (
+	"errors"
+)
+
+// ErrNoFrames reports an input animation without any frames.
+var ErrNoFrames = errors.New("minimum 1 input frame")
+
+// ErrInvalidFrame reports a frame that cannot be rendered, such as a nil image or an empty palette.
+var ErrInvalidFrame = errors.New("invalid frame")
+
+// ErrDelayCount reports a mismatch between frame and delay counts.
+var ErrDelayCount = errors.New("expected one delay per image")
+
+// ErrTooFewFrames reports edits that would leave no output frames.
+var ErrTooFewFrames = errors.New("minimum 1 output frame")
+
+// ErrWindowTooLong reports a window longer than the available frames.
+var ErrWindowTooLong = errors.New("window longer than subsequence")
+
+// ErrWindowTooShort reports a window shorter than one frame.
+var ErrWindowTooShort = errors.New("window cannot be less than one")
+
+// ErrCutIntervalTooShort reports a cut interval that would remove every frame.
+var ErrCutIntervalTooShort = errors.New("cut interval cannot be less than two")
+
+// ErrNegative reports a count that cannot be negative.
+var ErrNegative = errors.New("cannot be negative")
+
+// ErrNotFinite reports a NaN or infinite factor.
+var ErrNotFinite = errors.New("must be finite")
+
+// ErrUnknownStitch reports a stitch name missing from the registry.
+var ErrUnknownStitch = errors.New("unknown stitch")
+
+// ErrInvalidStitchParam reports a missing, unknown, or malformed stitch parameter.
+var ErrInvalidStitchParam = errors.New("invalid stitch parameter")
+
+// ErrInvalidAPNG reports malformed APNG structure.
+var ErrInvalidAPNG = errors.New("invalid APNG")
+
+// ErrInvalidWebP reports malformed WebP structure or VP8L image data.
+var ErrInvalidWebP = errors.New("invalid WebP")
+
+// ErrUnsupportedWebP reports WebP features beyond lossless VP8L frames, such as lossy VP8 frames.
+var ErrUnsupportedWebP = errors.New("unsupported WebP")
+
+// ErrInvalidSpriteSheet reports sprite sheet metadata or grids inconsistent with the sheet image.
+var ErrInvalidSpriteSheet = errors.New("invalid sprite sheet")
+
+// ErrInvalidY4M reports malformed YUV4MPEG2 structure.
+var ErrInvalidY4M = errors.New("invalid Y4M")
+
+// ErrUnsupportedY4M reports YUV4MPEG2 color spaces beyond 4:2:0, 4:2:2, 4:4:4, and mono.
+var ErrUnsupportedY4M = errors.New("unsupported Y4M")
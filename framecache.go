@@ -0,0 +1,219 @@
+package buttery
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"sync"
+)
+
+// frameCacheKey indexes a frameCache within a context.
+type frameCacheKey struct{}
+
+// withFrameCache generates a context that holds transformed frames in the given cache.
+func withFrameCache(ctx context.Context, cache *frameCache) context.Context {
+	return context.WithValue(ctx, frameCacheKey{}, cache)
+}
+
+// getFrameCache queries the frame cache of a context.
+//
+// nil indicates frames live entirely in memory.
+func getFrameCache(ctx context.Context) *frameCache {
+	cache, _ := ctx.Value(frameCacheKey{}).(*frameCache)
+	return cache
+}
+
+// frameCache holds paletted frames in memory up to a byte limit,
+// spilling the remainder to a temporary file.
+type frameCache struct {
+	limit int64
+	mutex sync.Mutex
+	used  int64
+	file  *os.File
+	size  int64
+}
+
+// newFrameCache generates a frameCache with the given memory limit in bytes.
+func newFrameCache(limit int64) *frameCache {
+	return &frameCache{limit: limit}
+}
+
+// store admits an image to the cache,
+// returning either the image itself or a spilled stand in.
+//
+// Images other than *image.Paletted and *encodedImage remain in memory.
+func (o *frameCache) store(img image.Image) (image.Image, error) {
+	if encoded, ok := img.(*encodedImage); ok {
+		return o.storeEncoded(encoded)
+	}
+
+	paletted, ok := img.(*image.Paletted)
+
+	if !ok {
+		return img, nil
+	}
+
+	bounds := paletted.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	file, offset, err := o.admit(int64(width*height+4*len(paletted.Palette)), int64(width*height))
+
+	if err != nil || file == nil {
+		return paletted, err
+	}
+
+	for y := 0; y < height; y++ {
+		start := paletted.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+
+		if _, err := file.WriteAt(paletted.Pix[start:start+width], offset+int64(y*width)); err != nil {
+			return nil, err
+		}
+	}
+
+	return &spilledImage{file: file, offset: offset, rect: bounds, palette: paletted.Palette}, nil
+}
+
+// storeEncoded admits an original image block to the cache,
+// spilling the block itself beyond the limit.
+func (o *frameCache) storeEncoded(encoded *encodedImage) (image.Image, error) {
+	if encoded.file != nil {
+		return encoded, nil
+	}
+
+	file, offset, err := o.admit(int64(len(encoded.block)), int64(len(encoded.block)))
+
+	if err != nil || file == nil {
+		return encoded, err
+	}
+
+	if _, err := file.WriteAt(encoded.block, offset); err != nil {
+		return nil, err
+	}
+
+	return &encodedImage{
+		header:           encoded.header,
+		file:             file,
+		offset:           offset,
+		size:             len(encoded.block),
+		transparentIndex: encoded.transparentIndex,
+		rect:             encoded.rect,
+		palette:          encoded.palette,
+	}, nil
+}
+
+// admit counts n bytes toward the memory limit,
+// or else reserves size bytes of the spill file.
+//
+// A nil file indicates the bytes fit in memory.
+func (o *frameCache) admit(n, size int64) (*os.File, int64, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.used+n <= o.limit {
+		o.used += n
+		return nil, 0, nil
+	}
+
+	if o.file == nil {
+		file, err := os.CreateTemp("", "buttery-*.frames")
+		if err != nil {
+			return nil, 0, err
+		}
+
+		o.file = file
+	}
+
+	offset := o.size
+	o.size += size
+	return o.file, offset, nil
+}
+
+// load reads a spilled or encoded image back into memory.
+//
+// Other images pass through unmodified.
+func (o *frameCache) load(img image.Image) (image.Image, error) {
+	if encoded, ok := img.(*encodedImage); ok {
+		return encoded.decode()
+	}
+
+	spilled, ok := img.(*spilledImage)
+
+	if !ok {
+		return img, nil
+	}
+
+	paletted := image.NewPaletted(spilled.rect, spilled.palette)
+
+	if _, err := spilled.file.ReadAt(paletted.Pix, spilled.offset); err != nil {
+		return nil, err
+	}
+
+	return paletted, nil
+}
+
+// Close removes any spill file.
+func (o *frameCache) Close() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.file == nil {
+		return nil
+	}
+
+	pth := o.file.Name()
+
+	err := o.file.Close()
+	o.file = nil
+	return errors.Join(err, os.Remove(pth))
+}
+
+// spilledImage models a paletted frame stored in a frameCache spill file.
+//
+// Pixel queries read through to the file,
+// so bulk consumers should load the image first.
+type spilledImage struct {
+	file    *os.File
+	offset  int64
+	rect    image.Rectangle
+	palette color.Palette
+}
+
+// ColorModel queries the palette.
+func (o *spilledImage) ColorModel() color.Model {
+	return o.palette
+}
+
+// Bounds queries the image dimensions.
+func (o *spilledImage) Bounds() image.Rectangle {
+	return o.rect
+}
+
+// At queries the color of a pixel.
+func (o *spilledImage) At(x, y int) color.Color {
+	if len(o.palette) == 0 {
+		return color.Transparent
+	}
+
+	return o.palette[o.ColorIndexAt(x, y)]
+}
+
+// ColorIndexAt queries the palette index of a pixel.
+func (o *spilledImage) ColorIndexAt(x, y int) uint8 {
+	if !(image.Point{X: x, Y: y}.In(o.rect)) {
+		return 0
+	}
+
+	offset := int64((y-o.rect.Min.Y)*o.rect.Dx() + (x - o.rect.Min.X))
+	var index [1]byte
+
+	if _, err := o.file.ReadAt(index[:], o.offset+offset); err != nil {
+		return 0
+	}
+
+	if int(index[0]) >= len(o.palette) {
+		return 0
+	}
+
+	return index[0]
+}
@@ -0,0 +1,200 @@
+package buttery_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+// encodeTestGIF generates a small animation with the given frame count.
+func encodeTestGIF(t testing.TB, frames int) []byte {
+	palette := color.Palette{color.Black, color.White, color.Transparent}
+	sourceGif := &gif.GIF{}
+
+	for i := 0; i < frames; i++ {
+		paletted := image.NewPaletted(image.Rect(0, 0, 4, 3), palette)
+		paletted.SetColorIndex(i%4, i%3, 1)
+		sourceGif.Image = append(sourceGif.Image, paletted)
+		sourceGif.Delay = append(sourceGif.Delay, 4)
+	}
+
+	var buf bytes.Buffer
+
+	if err := gif.EncodeAll(&buf, sourceGif); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestValidateFor(t *testing.T) {
+	sourceGif, err := gif.DecodeAll(bytes.NewReader(encodeTestGIF(t, 3)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := buttery.NewConfig()
+	config.TrimEdges = 1
+	config.Window = 2
+	config.CutInterval = 1
+
+	err = config.ValidateFor(sourceGif)
+
+	if !errors.Is(err, buttery.ErrWindowTooLong) {
+		t.Errorf("expected ErrWindowTooLong, got %v", err)
+	}
+
+	if !errors.Is(err, buttery.ErrCutIntervalTooShort) {
+		t.Errorf("expected every problem reported, got %v", err)
+	}
+
+	config = buttery.NewConfig()
+	config.TrimEdges = 2
+
+	if _, err := config.EditGIF(sourceGif); !errors.Is(err, buttery.ErrTooFewFrames) {
+		t.Errorf("expected ErrTooFewFrames, got %v", err)
+	}
+
+	if _, err := config.EditGIF(&gif.GIF{}); !errors.Is(err, buttery.ErrNoFrames) {
+		t.Errorf("expected ErrNoFrames, got %v", err)
+	}
+}
+
+func FuzzEdit(f *testing.F) {
+	f.Add(encodeTestGIF(f, 1), 0, 0, 0, 0, 0, 0, "Mirror")
+	f.Add(encodeTestGIF(f, 3), 1, 0, 1, 2, 0, -1, "FlipH")
+	f.Add(encodeTestGIF(f, 5), 0, 2, 2, 0, 2, 3, "Fade:color=0xffffff,rate=2")
+	f.Add(encodeTestGIF(f, 4), 0, 0, 0, 0, 0, 1, "PanV:velocity=-1.5")
+
+	f.Fuzz(func(t *testing.T, data []byte, trimEdges, trimStart, trimEnd, window, cutInterval, shift int, stitch string) {
+		config, err := gif.DecodeConfig(bytes.NewReader(data))
+
+		if err != nil || config.Width*config.Height > 1<<16 {
+			return
+		}
+
+		sourceGif, err := gif.DecodeAll(bytes.NewReader(data))
+
+		if err != nil || len(sourceGif.Image) > 64 {
+			return
+		}
+
+		butteryConfig := buttery.NewConfig()
+		butteryConfig.TrimEdges = trimEdges
+		butteryConfig.TrimStart = trimStart
+		butteryConfig.TrimEnd = trimEnd
+		butteryConfig.Window = window
+		butteryConfig.CutInterval = cutInterval
+		butteryConfig.Shift = shift
+
+		if err := butteryConfig.Stitch.UnmarshalText([]byte(stitch)); err != nil {
+			return
+		}
+
+		validateErr := butteryConfig.ValidateFor(sourceGif)
+		butteryGif, err := butteryConfig.EditGIF(sourceGif)
+
+		if validateErr != nil {
+			if err == nil {
+				t.Errorf("expected EditGIF to reject config failing ValidateFor: %v", validateErr)
+			}
+
+			return
+		}
+
+		if err != nil {
+			return
+		}
+
+		if err := gif.EncodeAll(&bytes.Buffer{}, butteryGif); err != nil {
+			t.Errorf("expected encodable output, got %v", err)
+		}
+	})
+}
+
+func FuzzDecodeAPNG(f *testing.F) {
+	var buf bytes.Buffer
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.Pix[3] = 0xFF
+	animation := &buttery.Animation{Timeline: buttery.Timeline{{Image: img, Delay: 4}, {Image: image.NewNRGBA(img.Rect), Delay: 6}}}
+
+	if err := buttery.EncodeAPNG(&buf, animation); err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add(buf.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		config, err := png.DecodeConfig(bytes.NewReader(data))
+
+		if err != nil || config.Width*config.Height > 1<<16 {
+			return
+		}
+
+		animation, err := buttery.DecodeAPNG(bytes.NewReader(data))
+
+		if err != nil {
+			return
+		}
+
+		if err := buttery.EncodeAPNG(&bytes.Buffer{}, animation); err != nil {
+			t.Errorf("expected encodable output, got %v", err)
+		}
+	})
+}
+
+// webpArea measures the largest image area declared by VP8X and VP8L headers anywhere within a WebP.
+func webpArea(data []byte) int {
+	area := 0
+
+	for i := range data {
+		switch {
+		case bytes.HasPrefix(data[i:], []byte("VP8X")) && len(data) >= i+18:
+			area = max(area, (int(data[i+12])|int(data[i+13])<<8|int(data[i+14])<<16+1)*(int(data[i+15])|int(data[i+16])<<8|int(data[i+17])<<16+1))
+		case bytes.HasPrefix(data[i:], []byte("VP8L")) && len(data) >= i+13:
+			bits := binary.LittleEndian.Uint32(data[i+9:])
+			area = max(area, int(bits&0x3FFF+1)*int(bits>>14&0x3FFF+1))
+		}
+	}
+
+	return area
+}
+
+func FuzzDecodeWebP(f *testing.F) {
+	var buf bytes.Buffer
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.Pix[3] = 0xFF
+	animation := &buttery.Animation{Timeline: buttery.Timeline{{Image: img, Delay: 4}, {Image: image.NewNRGBA(img.Rect), Delay: 6}}}
+
+	if err := buttery.EncodeWebP(&buf, animation); err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add(buf.Bytes())
+	f.Add([]byte("RIFF\x00\x00\x00\x00WEBPVP8L\x00\x00\x00\x00"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if webpArea(data) > 1<<16 {
+			return
+		}
+
+		animation, err := buttery.DecodeWebP(bytes.NewReader(data))
+
+		if err != nil {
+			return
+		}
+
+		// Canvases may exceed the VP8L size limit that bounds whole frames.
+		if err := buttery.EncodeWebP(&bytes.Buffer{}, animation); err != nil && !errors.Is(err, buttery.ErrUnsupportedWebP) {
+			t.Errorf("expected encodable output, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,322 @@
+package buttery
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+)
+
+// GIF block markers.
+const (
+	gifExtension      = 0x21
+	gifImage          = 0x2c
+	gifTrailer        = 0x3b
+	gifGraphicControl = 0xf9
+	gifColorTableFlag = 0x80
+)
+
+// gifColorTableSize calculates the byte length of a color table from a packed descriptor field.
+func gifColorTableSize(packed byte) int {
+	if packed&gifColorTableFlag == 0 {
+		return 0
+	}
+
+	return 3 << (packed&0x07 + 1)
+}
+
+// frameReader decodes a GIF one frame at a time.
+type frameReader struct {
+	r *bufio.Reader
+
+	// header holds the signature, logical screen descriptor, and global color table.
+	header []byte
+
+	// config describes the logical screen.
+	config image.Config
+
+	// backgroundIndex denotes the logical screen background color.
+	backgroundIndex byte
+
+	// graphicControl holds the latest graphic control extension.
+	graphicControl []byte
+}
+
+// newFrameReader parses a GIF header.
+func newFrameReader(r io.Reader) (*frameReader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, 13)
+
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, err
+	}
+
+	globalColorTable := make([]byte, gifColorTableSize(header[10]))
+
+	if _, err := io.ReadFull(br, globalColorTable); err != nil {
+		return nil, err
+	}
+
+	header = append(header, globalColorTable...)
+	config, err := gif.DecodeConfig(bytes.NewReader(header))
+	if err != nil {
+		return nil, err
+	}
+
+	return &frameReader{r: br, header: header, config: config, backgroundIndex: header[11]}, nil
+}
+
+// sourceFrame models a decoded frame alongside its original GIF image block.
+type sourceFrame struct {
+	paletted *image.Paletted
+	delay    int
+	disposal byte
+
+	// block holds the image descriptor, any local color table, and LZW image data.
+	block []byte
+
+	// transparentIndex denotes the palette index rendered clear, or -1 for none.
+	transparentIndex int
+}
+
+// Next decodes the next frame, returning io.EOF after the last frame.
+func (o *frameReader) Next() (*sourceFrame, error) {
+	for {
+		marker, err := o.r.ReadByte()
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		switch marker {
+		case gifTrailer:
+			return nil, io.EOF
+		case gifExtension:
+			label, err2 := o.r.ReadByte()
+			if err2 != nil {
+				return nil, io.ErrUnexpectedEOF
+			}
+
+			var block bytes.Buffer
+			block.Write([]byte{marker, label})
+
+			if err3 := copySubBlocks(&block, o.r); err3 != nil {
+				return nil, err3
+			}
+
+			if label == gifGraphicControl {
+				o.graphicControl = block.Bytes()
+			}
+		case gifImage:
+			return o.decodeImage()
+		default:
+			return nil, fmt.Errorf("gif: unknown block type: 0x%.2x", marker)
+		}
+	}
+}
+
+// decodeImage decodes an image block as a single frame GIF.
+func (o *frameReader) decodeImage() (*sourceFrame, error) {
+	var buf bytes.Buffer
+	buf.Write(o.header)
+	buf.Write(o.graphicControl)
+	blockStart := buf.Len()
+	transparentIndex := -1
+
+	if len(o.graphicControl) >= 7 && o.graphicControl[3]&0x01 != 0 {
+		transparentIndex = int(o.graphicControl[6])
+	}
+
+	buf.WriteByte(gifImage)
+	o.graphicControl = nil
+	descriptor := make([]byte, 9)
+
+	if _, err := io.ReadFull(o.r, descriptor); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	buf.Write(descriptor)
+
+	// Local color table and LZW minimum code size.
+	if _, err := io.CopyN(&buf, o.r, int64(gifColorTableSize(descriptor[8])+1)); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	if err := copySubBlocks(&buf, o.r); err != nil {
+		return nil, err
+	}
+
+	block := bytes.Clone(buf.Bytes()[blockStart:])
+	buf.WriteByte(gifTrailer)
+	frameGif, err := gif.DecodeAll(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sourceFrame{
+		paletted:         frameGif.Image[0],
+		delay:            frameGif.Delay[0],
+		disposal:         frameGif.Disposal[0],
+		block:            block,
+		transparentIndex: transparentIndex,
+	}, nil
+}
+
+// copySubBlocks copies a run of GIF data sub-blocks, including the terminator.
+func copySubBlocks(w *bytes.Buffer, r *bufio.Reader) error {
+	for {
+		size, err := r.ReadByte()
+		if err != nil {
+			return io.ErrUnexpectedEOF
+		}
+
+		w.WriteByte(size)
+
+		if size == 0 {
+			return nil
+		}
+
+		if _, err2 := io.CopyN(w, r, int64(size)); err2 != nil {
+			return io.ErrUnexpectedEOF
+		}
+	}
+}
+
+// frameWriter encodes a GIF one frame at a time.
+//
+// Output matches gif.EncodeAll byte for byte,
+// apart from any frames copied verbatim with WriteBlock.
+type frameWriter struct {
+	w               *bufio.Writer
+	config          image.Config
+	backgroundIndex byte
+	loopCount       int
+	header          []byte
+	first           []byte
+	frames          int
+}
+
+// newFrameWriter generates a frameWriter.
+func newFrameWriter(w io.Writer, config image.Config, backgroundIndex byte, loopCount int) (*frameWriter, error) {
+	palette, ok := config.ColorModel.(color.Palette)
+
+	if !ok || len(palette) == 0 {
+		palette = color.Palette{color.Black}
+	}
+
+	o := &frameWriter{w: bufio.NewWriter(w), config: config, backgroundIndex: backgroundIndex, loopCount: loopCount}
+	encoded, err := o.encode(image.NewPaletted(image.Rect(0, 0, 1, 1), palette), 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	o.header = encoded[:13+gifColorTableSize(encoded[10])]
+	return o, nil
+}
+
+// encode renders a frame as a single frame GIF sharing the output logical screen.
+func (o *frameWriter) encode(paletted *image.Paletted, delay int, disposal byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := gif.EncodeAll(&buf, &gif.GIF{
+		Image:           []*image.Paletted{paletted},
+		Delay:           []int{delay},
+		Disposal:        []byte{disposal},
+		LoopCount:       -1,
+		Config:          o.config,
+		BackgroundIndex: o.backgroundIndex,
+	}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Write encodes a frame.
+func (o *frameWriter) Write(paletted *image.Paletted, delay int, disposal byte) error {
+	encoded, err := o.encode(paletted, delay, disposal)
+	if err != nil {
+		return err
+	}
+
+	return o.writeFrame(encoded[len(o.header) : len(encoded)-1])
+}
+
+// WriteBlock copies an image block verbatim,
+// preceded by a graphic control extension with the given timing and transparency.
+//
+// A negative transparent index indicates no transparency.
+// Image blocks lacking a local color table must suit the global color table.
+func (o *frameWriter) WriteBlock(block []byte, delay int, disposal byte, transparentIndex int) error {
+	flags := (disposal & 0x07) << 2
+	var index byte
+
+	if transparentIndex >= 0 {
+		flags |= 0x01
+		index = byte(transparentIndex)
+	}
+
+	graphicControl := []byte{gifExtension, gifGraphicControl, 0x04, flags, byte(delay), byte(delay >> 8), index, 0x00}
+	return o.writeFrame(append(graphicControl, block...))
+}
+
+// writeFrame emits the bytes of a frame,
+// holding back the first frame until the need for a loop extension is known.
+func (o *frameWriter) writeFrame(frame []byte) error {
+	o.frames++
+
+	switch o.frames {
+	case 1:
+		o.first = frame
+		return nil
+	case 2:
+		if err := o.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	_, err := o.w.Write(frame)
+	return err
+}
+
+// writeHeader emits the header, any loop extension, and the held back first frame.
+func (o *frameWriter) writeHeader() error {
+	if _, err := o.w.Write(o.header); err != nil {
+		return err
+	}
+
+	if o.frames > 1 && o.loopCount >= 0 {
+		loop := []byte{gifExtension, 0xff, 0x0b}
+		loop = append(loop, "NETSCAPE2.0"...)
+		loop = append(loop, 0x03, 0x01, byte(o.loopCount), byte(o.loopCount>>8), 0x00)
+
+		if _, err := o.w.Write(loop); err != nil {
+			return err
+		}
+	}
+
+	_, err := o.w.Write(o.first)
+	o.first = nil
+	return err
+}
+
+// Close emits the trailer.
+func (o *frameWriter) Close() error {
+	switch o.frames {
+	case 0:
+		return errors.New("gif: must provide at least one image")
+	case 1:
+		if err := o.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	if err := o.w.WriteByte(gifTrailer); err != nil {
+		return err
+	}
+
+	return o.w.Flush()
+}
@@ -3,6 +3,7 @@ module github.com/mcandre/buttery
 go 1.27.0
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/andybons/gogif v0.0.0-20140526152223-16d573594812
 	github.com/anthonynsimon/bild v0.17.0
 	github.com/magefile/mage v1.17.2
@@ -18,7 +19,6 @@ tool (
 )
 
 require (
-	github.com/BurntSushi/toml v1.6.0 // indirect
 	github.com/alexkohler/nakedret/v2 v2.0.6 // indirect
 	github.com/kisielk/errcheck v1.9.0 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
@@ -0,0 +1,100 @@
+package buttery
+
+import (
+	"image/color"
+	"image/gif"
+)
+
+// Info models GIF metadata.
+type Info struct {
+	// Width denotes the logical screen width.
+	Width int `json:"width"`
+
+	// Height denotes the logical screen height.
+	Height int `json:"height"`
+
+	// LoopCount follows gif.GIF.LoopCount.
+	LoopCount int `json:"loopCount"`
+
+	// BackgroundIndex denotes the global color table index of the background color.
+	BackgroundIndex int `json:"backgroundIndex"`
+
+	// GlobalPaletteSize denotes the size of the global color table, or zero for none.
+	GlobalPaletteSize int `json:"globalPaletteSize"`
+
+	// Duration sums the frame delays, in centiseconds.
+	Duration int `json:"duration"`
+
+	// Frames describes each frame.
+	Frames []FrameInfo `json:"frames"`
+}
+
+// FrameInfo models the metadata of a GIF frame.
+type FrameInfo struct {
+	// X, Y, Width, and Height denote the frame bounds within the logical screen.
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Delay denotes the frame duration, in centiseconds.
+	Delay int `json:"delay"`
+
+	// Disposal denotes the disposal method, per gif.DisposalNone and friends.
+	Disposal byte `json:"disposal"`
+
+	// PaletteSize denotes the size of the color table in effect.
+	PaletteSize int `json:"paletteSize"`
+
+	// TransparentIndex denotes the palette index rendered clear, or -1 for none.
+	TransparentIndex int `json:"transparentIndex"`
+}
+
+// GetInfo reports GIF metadata.
+func GetInfo(sourceGif *gif.GIF) Info {
+	info := Info{
+		Width:           sourceGif.Config.Width,
+		Height:          sourceGif.Config.Height,
+		LoopCount:       sourceGif.LoopCount,
+		BackgroundIndex: int(sourceGif.BackgroundIndex),
+		Frames:          make([]FrameInfo, len(sourceGif.Image)),
+	}
+
+	if palette, ok := sourceGif.Config.ColorModel.(color.Palette); ok {
+		info.GlobalPaletteSize = len(palette)
+	}
+
+	for i, paletted := range sourceGif.Image {
+		frameInfo := FrameInfo{Disposal: disposalAt(sourceGif, i), TransparentIndex: -1}
+
+		if i < len(sourceGif.Delay) {
+			frameInfo.Delay = sourceGif.Delay[i]
+		}
+
+		if paletted != nil {
+			frameInfo.X, frameInfo.Y = paletted.Rect.Min.X, paletted.Rect.Min.Y
+			frameInfo.Width, frameInfo.Height = paletted.Rect.Dx(), paletted.Rect.Dy()
+			frameInfo.PaletteSize = len(paletted.Palette)
+			frameInfo.TransparentIndex = clearIndex(paletted.Palette)
+		}
+
+		info.Duration += frameInfo.Delay
+		info.Frames[i] = frameInfo
+	}
+
+	return info
+}
+
+// clearIndex locates the clear entry of a decoded palette, or -1 for none.
+//
+// GIF color tables hold opaque colors,
+// so the decoder marks the transparent index by clearing its entry.
+func clearIndex(palette color.Palette) int {
+	for i, c := range palette {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			return i
+		}
+	}
+
+	return -1
+}
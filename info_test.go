@@ -0,0 +1,43 @@
+package buttery_test
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"reflect"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+func TestGetInfo(t *testing.T) {
+	palette := color.Palette{color.Black, color.White, color.RGBA{}}
+	sourceGif := &gif.GIF{
+		Image: []*image.Paletted{
+			image.NewPaletted(image.Rect(0, 0, 4, 3), palette[:2]),
+			image.NewPaletted(image.Rect(1, 1, 3, 2), palette),
+		},
+		Delay:           []int{5, 7},
+		Disposal:        []byte{gif.DisposalNone, gif.DisposalBackground},
+		LoopCount:       -1,
+		BackgroundIndex: 1,
+		Config:          image.Config{ColorModel: palette[:2], Width: 4, Height: 3},
+	}
+
+	expected := buttery.Info{
+		Width:             4,
+		Height:            3,
+		LoopCount:         -1,
+		BackgroundIndex:   1,
+		GlobalPaletteSize: 2,
+		Duration:          12,
+		Frames: []buttery.FrameInfo{
+			{Width: 4, Height: 3, Delay: 5, Disposal: gif.DisposalNone, PaletteSize: 2, TransparentIndex: -1},
+			{X: 1, Y: 1, Width: 2, Height: 1, Delay: 7, Disposal: gif.DisposalBackground, PaletteSize: 3, TransparentIndex: 2},
+		},
+	}
+
+	if info := buttery.GetInfo(sourceGif); !reflect.DeepEqual(info, expected) {
+		t.Errorf("expected %+v, got %+v", expected, info)
+	}
+}
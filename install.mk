@@ -6,7 +6,6 @@ all:
 	cargo install --force rockhopper@0.0.25
 	go install golang.org/x/tools/cmd/deadcode@latest
 	go install golang.org/x/tools/cmd/goimports@latest
-	go install golang.org/x/tools/cmd/stringer@latest
 	go install golang.org/x/tools/go/analysis/passes/shadow/cmd/shadow@latest
 	go install golang.org/x/vuln/cmd/govulncheck@latest
 	go install tool
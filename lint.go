@@ -0,0 +1,228 @@
+package buttery
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"slices"
+	"strings"
+)
+
+// Severity ranks lint issues.
+type Severity int
+
+const (
+	// SeverityInfo marks harmless observations, such as wasted bytes.
+	SeverityInfo Severity = iota
+
+	// SeverityWarning marks GIFs likely to play differently across viewers.
+	SeverityWarning
+
+	// SeverityError marks malformed GIFs.
+	SeverityError
+)
+
+// severityNames labels severities.
+var severityNames = []string{"info", "warning", "error"}
+
+// String renders the severity name.
+func (o Severity) String() string {
+	if o < 0 || int(o) >= len(severityNames) {
+		return fmt.Sprintf("Severity(%d)", int(o))
+	}
+
+	return severityNames[o]
+}
+
+// MarshalText renders the severity name.
+func (o Severity) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText parses a severity name.
+func (o *Severity) UnmarshalText(text []byte) error {
+	i := slices.IndexFunc(severityNames, func(name string) bool {
+		return strings.EqualFold(name, string(text))
+	})
+
+	if i < 0 {
+		return fmt.Errorf("unknown severity: %v", string(text))
+	}
+
+	*o = Severity(i)
+	return nil
+}
+
+// Issue models a lint finding.
+type Issue struct {
+	Severity Severity `json:"severity"`
+
+	// Code identifies the kind of issue, such as short-delay.
+	Code string `json:"code"`
+
+	// Frame denotes the offending frame index, or -1 for the GIF as a whole.
+	Frame int `json:"frame"`
+
+	Message string `json:"message"`
+}
+
+// String renders the issue.
+func (o Issue) String() string {
+	if o.Frame < 0 {
+		return fmt.Sprintf("%v: %v: %v", o.Severity, o.Code, o.Message)
+	}
+
+	return fmt.Sprintf("%v: frame %d: %v: %v", o.Severity, o.Frame, o.Code, o.Message)
+}
+
+// WorstSeverity queries the highest severity among issues,
+// or -1 for none.
+func WorstSeverity(issues []Issue) Severity {
+	worst := Severity(-1)
+
+	for _, issue := range issues {
+		worst = max(worst, issue.Severity)
+	}
+
+	return worst
+}
+
+// Lint inspects a decoded GIF for problems beyond basic format integrity,
+// reporting issues in frame order.
+func Lint(sourceGif *gif.GIF) []Issue {
+	var issues []Issue
+	report := func(severity Severity, code string, frame int, format string, args ...any) {
+		issues = append(issues, Issue{Severity: severity, Code: code, Frame: frame, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if err := validateSource(sourceGif); err != nil {
+		report(SeverityError, "invalid", -1, "%v", err)
+		return issues
+	}
+
+	frames := len(sourceGif.Image)
+
+	if len(sourceGif.Delay) != frames {
+		report(SeverityError, "delay-count", -1, "%d delays for %d frames", len(sourceGif.Delay), frames)
+	}
+
+	if len(sourceGif.Disposal) != 0 && len(sourceGif.Disposal) != frames {
+		report(SeverityError, "disposal-count", -1, "%d disposal methods for %d frames", len(sourceGif.Disposal), frames)
+	}
+
+	if frames > 1 && sourceGif.LoopCount < 0 {
+		report(SeverityWarning, "no-loop", -1, "missing loop extension, so most viewers play the animation once")
+	}
+
+	globalPalette, _ := sourceGif.Config.ColorModel.(color.Palette)
+
+	if len(globalPalette) > 256 {
+		report(SeverityError, "palette-size", -1, "global palette holds %d colors, exceeding 256", len(globalPalette))
+	}
+
+	if len(globalPalette) > 0 && int(sourceGif.BackgroundIndex) >= len(globalPalette) {
+		report(SeverityWarning, "background-index", -1, "background index %d exceeds global palette size %d", sourceGif.BackgroundIndex, len(globalPalette))
+	}
+
+	screen := screenBounds(sourceGif)
+
+	for i, paletted := range sourceGif.Image {
+		issues = append(issues, lintFrame(sourceGif, i, screen)...)
+
+		if i > 0 && sameFrame(sourceGif.Image[i-1], paletted) && disposalAt(sourceGif, i-1) == disposalAt(sourceGif, i) {
+			report(SeverityInfo, "duplicate-frame", i, "duplicates frame %d, and could merge into its delay instead", i-1)
+		}
+	}
+
+	return issues
+}
+
+// lintFrame inspects a single frame.
+func lintFrame(sourceGif *gif.GIF, i int, screen image.Rectangle) []Issue {
+	var issues []Issue
+	report := func(severity Severity, code string, format string, args ...any) {
+		issues = append(issues, Issue{Severity: severity, Code: code, Frame: i, Message: fmt.Sprintf(format, args...)})
+	}
+
+	paletted := sourceGif.Image[i]
+
+	if !paletted.Rect.In(screen) {
+		if paletted.Rect.Overlaps(screen) {
+			report(SeverityWarning, "frame-bounds", "bounds %v extend beyond the logical screen %v, which viewers crop or grow differently", paletted.Rect, screen)
+		} else {
+			report(SeverityWarning, "frame-bounds", "bounds %v lie outside the logical screen %v, rendering nothing", paletted.Rect, screen)
+		}
+	}
+
+	if i < len(sourceGif.Delay) && sourceGif.Delay[i] < 2 {
+		report(SeverityWarning, "short-delay", "delay of %dcs, which browsers clamp to 10cs", sourceGif.Delay[i])
+	}
+
+	if len(paletted.Palette) > 256 {
+		report(SeverityError, "palette-size", "palette holds %d colors, exceeding 256", len(paletted.Palette))
+	}
+
+	var maxIndex int
+
+	for y := paletted.Rect.Min.Y; y < paletted.Rect.Max.Y; y++ {
+		offset := paletted.PixOffset(paletted.Rect.Min.X, y)
+
+		for _, index := range paletted.Pix[offset : offset+paletted.Rect.Dx()] {
+			maxIndex = max(maxIndex, int(index))
+		}
+	}
+
+	if maxIndex >= len(paletted.Palette) {
+		report(SeverityError, "color-index", "pixel color index %d exceeds palette size %d", maxIndex, len(paletted.Palette))
+	} else if size := colorTableSize(maxIndex + 1); size < len(paletted.Palette) {
+		report(SeverityInfo, "palette-oversized", "palette holds %d colors, but a %d color table suffices", len(paletted.Palette), size)
+	}
+
+	disposal := disposalAt(sourceGif, i)
+
+	switch {
+	case disposal > gif.DisposalPrevious:
+		report(SeverityError, "disposal", "reserved disposal method %d", disposal)
+	case disposal == gif.DisposalPrevious && i == 0:
+		report(SeverityWarning, "disposal", "first frame restores to previous, leaving no defined screen state")
+	case disposal == gif.DisposalBackground && paletted.Rect != screen:
+		report(SeverityWarning, "disposal", "partial frame restores to background, which browsers clear to transparent but the GIF spec fills with the background color")
+	}
+
+	return issues
+}
+
+// colorTableSize rounds a color count up to a GIF color table size.
+func colorTableSize(colors int) int {
+	size := 2
+
+	for size < colors {
+		size <<= 1
+	}
+
+	return size
+}
+
+// sameFrame reports whether two frames hold identical bounds, pixels, and palettes.
+func sameFrame(a, b *image.Paletted) bool {
+	if a.Rect != b.Rect || !bytes.Equal(a.Pix, b.Pix) || len(a.Palette) != len(b.Palette) {
+		return false
+	}
+
+	for i := range a.Palette {
+		if !sameColor(a.Palette[i], b.Palette[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// sameColor reports whether two colors match once premultiplied.
+func sameColor(a, b color.Color) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
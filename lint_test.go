@@ -0,0 +1,65 @@
+package buttery_test
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"slices"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+func TestLint(t *testing.T) {
+	palette := color.Palette{color.Black, color.White, color.RGBA{R: 0xFF, A: 0xFF}, color.RGBA{G: 0xFF, A: 0xFF}}
+	frame := image.NewPaletted(image.Rect(0, 0, 4, 4), palette)
+	sourceGif := &gif.GIF{
+		Image: []*image.Paletted{
+			frame,
+			frame,
+			image.NewPaletted(image.Rect(2, 2, 6, 6), palette[:2]),
+		},
+		Delay:           []int{10, 10, 1},
+		Disposal:        []byte{gif.DisposalPrevious, gif.DisposalPrevious, gif.DisposalBackground},
+		LoopCount:       -1,
+		BackgroundIndex: 4,
+		Config:          image.Config{ColorModel: palette, Width: 4, Height: 4},
+	}
+
+	type finding struct {
+		severity buttery.Severity
+		code     string
+		frame    int
+	}
+
+	expected := []finding{
+		{buttery.SeverityWarning, "no-loop", -1},
+		{buttery.SeverityWarning, "background-index", -1},
+		{buttery.SeverityInfo, "palette-oversized", 0},
+		{buttery.SeverityWarning, "disposal", 0},
+		{buttery.SeverityInfo, "palette-oversized", 1},
+		{buttery.SeverityInfo, "duplicate-frame", 1},
+		{buttery.SeverityWarning, "frame-bounds", 2},
+		{buttery.SeverityWarning, "short-delay", 2},
+		{buttery.SeverityWarning, "disposal", 2},
+	}
+
+	issues := buttery.Lint(sourceGif)
+	var findings []finding
+
+	for _, issue := range issues {
+		findings = append(findings, finding{issue.Severity, issue.Code, issue.Frame})
+	}
+
+	if !slices.Equal(findings, expected) {
+		t.Errorf("expected findings %v, got %v", expected, issues)
+	}
+
+	if worst := buttery.WorstSeverity(issues); worst != buttery.SeverityWarning {
+		t.Errorf("expected worst severity warning, got %v", worst)
+	}
+
+	if worst := buttery.WorstSeverity(buttery.Lint(&gif.GIF{})); worst != buttery.SeverityError {
+		t.Errorf("expected empty GIFs to lint as errors, got %v", worst)
+	}
+}
@@ -0,0 +1,328 @@
+package buttery
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Operation models a single animation editing stage.
+//
+// Operations may be repeated and reordered within a Pipeline.
+type Operation interface {
+	// Validate checks for basic Operation integrity.
+	Validate() error
+
+	// String renders the Operation in ParseOperation syntax.
+	String() string
+
+	// Apply transforms a Timeline.
+	//
+	// Long running Operations should honor cancellation,
+	// and may report progress with ReportProgress.
+	Apply(ctx context.Context, timeline Timeline) (Timeline, error)
+}
+
+// Reverse plays the sequence backwards.
+type Reverse struct{}
+
+// Validate checks for basic Reverse integrity.
+func (o Reverse) Validate() error { return nil }
+
+// String renders Reverse in ParseOperation syntax.
+func (o Reverse) String() string { return "reverse" }
+
+// Apply plays the Timeline backwards.
+func (o Reverse) Apply(_ context.Context, timeline Timeline) (Timeline, error) {
+	return timeline.Reverse(), nil
+}
+
+// Trim removes frames from the start and end of the sequence.
+type Trim struct {
+	// Start denotes how many leading frames to remove.
+	Start int
+
+	// End denotes how many trailing frames to remove.
+	End int
+}
+
+// Validate checks for basic Trim integrity.
+func (o Trim) Validate() error {
+	if o.Start < 0 {
+		return fmt.Errorf("trim start %w", ErrNegative)
+	}
+
+	if o.End < 0 {
+		return fmt.Errorf("trim end %w", ErrNegative)
+	}
+
+	return nil
+}
+
+// String renders Trim in ParseOperation syntax.
+func (o Trim) String() string { return fmt.Sprintf("trim:%d,%d", o.Start, o.End) }
+
+// Apply trims the Timeline.
+func (o Trim) Apply(_ context.Context, timeline Timeline) (Timeline, error) {
+	return timeline.Trim(o.Start, o.End)
+}
+
+// Window truncates the sequence to a fixed frame count.
+type Window struct {
+	// Length denotes the resulting frame count.
+	Length int
+}
+
+// Validate checks for basic Window integrity.
+func (o Window) Validate() error {
+	if o.Length < 1 {
+		return ErrWindowTooShort
+	}
+
+	return nil
+}
+
+// String renders Window in ParseOperation syntax.
+func (o Window) String() string { return fmt.Sprintf("window:%d", o.Length) }
+
+// Apply truncates the Timeline.
+func (o Window) Apply(_ context.Context, timeline Timeline) (Timeline, error) {
+	return timeline.Window(o.Length)
+}
+
+// Cut removes every nth frame from the sequence.
+type Cut struct {
+	// Interval denotes n.
+	Interval int
+}
+
+// Validate checks for basic Cut integrity.
+func (o Cut) Validate() error {
+	if o.Interval < 2 {
+		return ErrCutIntervalTooShort
+	}
+
+	return nil
+}
+
+// String renders Cut in ParseOperation syntax.
+func (o Cut) String() string { return fmt.Sprintf("cut:%d", o.Interval) }
+
+// Apply cuts frames from the Timeline.
+func (o Cut) Apply(_ context.Context, timeline Timeline) (Timeline, error) {
+	return timeline.Cut(o.Interval)
+}
+
+// Shift rotates the sequence leftward.
+//
+// A negative offset rotates the sequence rightward.
+type Shift struct {
+	// Offset denotes the rotation distance.
+	Offset int
+}
+
+// Validate checks for basic Shift integrity.
+func (o Shift) Validate() error { return nil }
+
+// String renders Shift in ParseOperation syntax.
+func (o Shift) String() string { return fmt.Sprintf("shift:%d", o.Offset) }
+
+// Apply rotates the Timeline.
+func (o Shift) Apply(_ context.Context, timeline Timeline) (Timeline, error) {
+	return timeline.Shift(o.Offset), nil
+}
+
+// ScaleDelay multiplies each frame delay by a factor.
+//
+// The resulting delay is upheld to a lower bound of 2 centisec.
+type ScaleDelay struct {
+	// Factor denotes the delay multiplier.
+	Factor float64
+}
+
+// Validate checks for basic ScaleDelay integrity.
+func (o ScaleDelay) Validate() error {
+	if math.IsNaN(o.Factor) || math.IsInf(o.Factor, 0) {
+		return fmt.Errorf("scale delay factor %w", ErrNotFinite)
+	}
+
+	if o.Factor < 0 {
+		return fmt.Errorf("scale delay factor %w", ErrNegative)
+	}
+
+	return nil
+}
+
+// String renders ScaleDelay in ParseOperation syntax.
+func (o ScaleDelay) String() string {
+	return fmt.Sprintf("delay:%s", strconv.FormatFloat(o.Factor, 'g', -1, 64))
+}
+
+// Apply scales the Timeline delays.
+func (o ScaleDelay) Apply(_ context.Context, timeline Timeline) (Timeline, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	return timeline.ScaleDelay(o.Factor), nil
+}
+
+// StitchStage applies a loop continuity transition.
+type StitchStage struct {
+	// Stitch denotes the transition.
+	Stitch Stitch
+}
+
+// Validate checks for basic StitchStage integrity.
+func (o StitchStage) Validate() error { return o.Stitch.Validate() }
+
+// String renders StitchStage in ParseOperation syntax.
+func (o StitchStage) String() string { return o.Stitch.String() }
+
+// Apply stitches the Timeline.
+func (o StitchStage) Apply(ctx context.Context, timeline Timeline) (Timeline, error) {
+	stitcher, err := o.Stitch.Stitcher()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stitcher.Stitch(ctx, timeline)
+}
+
+// ParseOperations generates a sequence of Operations
+// from a pipe (|) delimited string value.
+//
+// Example: "trim:2,2|mirror|fade:0xffffff"
+func ParseOperations(s string) ([]Operation, error) {
+	var operations []Operation
+
+	for _, stage := range strings.Split(s, "|") {
+		operation, err := ParseOperation(stage)
+
+		if err != nil {
+			return nil, err
+		}
+
+		operations = append(operations, operation)
+	}
+
+	return operations, nil
+}
+
+// ParseOperation generates an Operation from a string value,
+// of the form name[:arg[,arg...]].
+//
+// Names are case insensitive:
+//
+//   - reverse
+//   - trim:<n> or trim:<start>,<end>
+//   - window:<n>
+//   - cut:<n>
+//   - shift:<offset>
+//   - delay:<factor>
+//   - any registered stitch, in Stitch text form
+//     (e.g. mirror, panh:2, fade:0xffffff,rate=0.5)
+func ParseOperation(s string) (Operation, error) {
+	name, argsString, _ := strings.Cut(strings.TrimSpace(s), ":")
+	var args []string
+
+	if argsString != "" {
+		args = strings.Split(argsString, ",")
+	}
+
+	ints := func(minArgs, maxArgs int) ([]int, error) {
+		if len(args) < minArgs || len(args) > maxArgs {
+			return nil, fmt.Errorf("operation %v expects %d to %d arguments", name, minArgs, maxArgs)
+		}
+
+		var xs []int
+
+		for _, arg := range args {
+			x, err := strconv.Atoi(strings.TrimSpace(arg))
+
+			if err != nil {
+				return nil, fmt.Errorf("operation %v: %v", name, err)
+			}
+
+			xs = append(xs, x)
+		}
+
+		return xs, nil
+	}
+
+	var operation Operation
+
+	switch strings.ToLower(name) {
+	case "reverse":
+		if len(args) != 0 {
+			return nil, fmt.Errorf("operation %v expects no arguments", name)
+		}
+
+		operation = Reverse{}
+	case "trim":
+		xs, err := ints(1, 2)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(xs) == 1 {
+			xs = append(xs, xs[0])
+		}
+
+		operation = Trim{Start: xs[0], End: xs[1]}
+	case "window":
+		xs, err := ints(1, 1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		operation = Window{Length: xs[0]}
+	case "cut":
+		xs, err := ints(1, 1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		operation = Cut{Interval: xs[0]}
+	case "shift":
+		xs, err := ints(1, 1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		operation = Shift{Offset: xs[0]}
+	case "delay":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("operation %v expects 1 argument", name)
+		}
+
+		factor, err := strconv.ParseFloat(strings.TrimSpace(args[0]), 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("operation %v: %v", name, err)
+		}
+
+		operation = ScaleDelay{Factor: factor}
+	default:
+		var stitch Stitch
+
+		if err := stitch.UnmarshalText([]byte(s)); err != nil {
+			return nil, err
+		}
+
+		operation = StitchStage{Stitch: stitch}
+	}
+
+	if err := operation.Validate(); err != nil {
+		return nil, err
+	}
+
+	return operation, nil
+}
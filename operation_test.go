@@ -0,0 +1,271 @@
+package buttery_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+func TestOperationMarshaling(t *testing.T) {
+	opsString := "reverse|trim:2,1|window:3|cut:2|Mirror|PanH:velocity=2|Fade:color=0xffffff,rate=0.5|shift:-1|delay:1.5"
+	operations, err := buttery.ParseOperations(opsString)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var opsStrings []string
+
+	for _, operation := range operations {
+		opsStrings = append(opsStrings, operation.String())
+	}
+
+	opsString2 := strings.Join(opsStrings, "|")
+
+	if opsString2 != opsString {
+		t.Errorf("expected symmetric marshaling for operations %v, got %v", opsString, opsString2)
+	}
+}
+
+func TestPipelineStitchesSeveralTimes(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	sourceGif := &gif.GIF{}
+
+	for i := 0; i < 3; i++ {
+		sourceGif.Image = append(sourceGif.Image, image.NewPaletted(image.Rect(0, 0, 2, 2), palette))
+		sourceGif.Delay = append(sourceGif.Delay, 4)
+	}
+
+	operations, err := buttery.ParseOperations("mirror|fliph|delay:2")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pipeline := buttery.Pipeline{Operations: operations}
+	butteryGif, err := pipeline.EditGIF(sourceGif)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(butteryGif.Image) != 10 {
+		t.Errorf("expected 10 frames, got %d", len(butteryGif.Image))
+	}
+
+	for i, delay := range butteryGif.Delay {
+		if delay != 8 {
+			t.Errorf("expected frame %d delay 8, got %d", i, delay)
+		}
+	}
+}
+
+func TestPipelineCancellation(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	sourceGif := &gif.GIF{
+		Image: []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 2, 2), palette)},
+		Delay: []int{4},
+	}
+
+	var reports int
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ctx = buttery.WithProgress(ctx, func(buttery.Progress) { reports++ })
+	pipeline := buttery.Pipeline{Operations: []buttery.Operation{buttery.StitchStage{Stitch: buttery.FlipH}}}
+
+	if _, err := pipeline.EditGIFContext(ctx, sourceGif); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancellation, got %v", err)
+	}
+
+	if reports != 0 {
+		t.Errorf("expected no progress reports after cancellation, got %d", reports)
+	}
+}
+
+func TestPipelineWorkersReproducible(t *testing.T) {
+	palette := color.Palette{color.Black, color.White, color.RGBA{R: 0xFF, A: 0xFF}, color.RGBA{B: 0xFF, A: 0xFF}}
+	sourceGif := &gif.GIF{}
+
+	for i := 0; i < 8; i++ {
+		paletted := image.NewPaletted(image.Rect(0, 0, 8, 8), palette)
+
+		for j := range paletted.Pix {
+			paletted.Pix[j] = uint8((i + j) % len(palette))
+		}
+
+		sourceGif.Image = append(sourceGif.Image, paletted)
+		sourceGif.Delay = append(sourceGif.Delay, 4)
+	}
+
+	operations, err := buttery.ParseOperations("mirror|flipv|panh:1|fade:0x00ff00")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var outputs []string
+
+	for _, workers := range []int{1, 4} {
+		var buf bytes.Buffer
+		pipeline := buttery.Pipeline{Operations: operations, Workers: workers}
+		butteryGif, err := pipeline.EditGIF(sourceGif)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := gif.EncodeAll(&buf, butteryGif); err != nil {
+			t.Fatal(err)
+		}
+
+		outputs = append(outputs, buf.String())
+	}
+
+	if outputs[0] != outputs[1] {
+		t.Errorf("expected identical output across worker counts")
+	}
+}
+
+func TestPipelineStreamMatchesInMemory(t *testing.T) {
+	palette := color.Palette{color.Black, color.White, color.RGBA{R: 0xFF, A: 0xFF}, color.RGBA{B: 0xFF, A: 0xFF}}
+	sourceGif := &gif.GIF{LoopCount: -1}
+
+	for i := 0; i < 6; i++ {
+		paletted := image.NewPaletted(image.Rect(0, 0, 8, 8), palette)
+
+		for j := range paletted.Pix {
+			paletted.Pix[j] = uint8((i * j) % len(palette))
+		}
+
+		sourceGif.Image = append(sourceGif.Image, paletted)
+		sourceGif.Delay = append(sourceGif.Delay, 3)
+	}
+
+	var source bytes.Buffer
+
+	if err := gif.EncodeAll(&source, sourceGif); err != nil {
+		t.Fatal(err)
+	}
+
+	operations, err := buttery.ParseOperations("trim:1,0|mirror|fliph|fade:0x00ff00|shift:2")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var outputs []string
+
+	// A one byte limit spills every frame.
+	for _, memoryLimit := range []int64{0, 1, 1 << 20} {
+		var buf bytes.Buffer
+		pipeline := buttery.Pipeline{Operations: operations, Workers: 3, MemoryLimit: memoryLimit}
+
+		if err := pipeline.EditTo(&buf, bytes.NewReader(source.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+
+		outputs = append(outputs, buf.String())
+	}
+
+	for i, output := range outputs[1:] {
+		if output != outputs[0] {
+			t.Errorf("expected streaming output %d identical to in memory output", i+1)
+		}
+	}
+}
+
+func TestPipelinePassthroughLossless(t *testing.T) {
+	sourceGif := &gif.GIF{LoopCount: -1, Config: image.Config{Width: 16, Height: 16}}
+
+	for i := 0; i < 3; i++ {
+		palette := make(color.Palette, 256)
+
+		for j := range palette {
+			palette[j] = color.RGBA{R: uint8(255 - j), G: uint8(j * (i + 3)), B: uint8(j), A: 0xFF}
+		}
+
+		paletted := image.NewPaletted(image.Rect(0, 0, 16, 16), palette)
+
+		for j := range paletted.Pix {
+			paletted.Pix[j] = uint8(j)
+		}
+
+		sourceGif.Image = append(sourceGif.Image, paletted)
+		sourceGif.Delay = append(sourceGif.Delay, 3)
+	}
+
+	var source bytes.Buffer
+
+	if err := gif.EncodeAll(&source, sourceGif); err != nil {
+		t.Fatal(err)
+	}
+
+	operations, err := buttery.ParseOperations("mirror|delay:2")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := []int{0, 1, 2, 1, 0}
+
+	for _, memoryLimit := range []int64{0, 1} {
+		var buf bytes.Buffer
+		pipeline := buttery.Pipeline{Operations: operations, MemoryLimit: memoryLimit}
+
+		if err := pipeline.EditTo(&buf, bytes.NewReader(source.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+
+		butteryGif, err := gif.DecodeAll(&buf)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(butteryGif.Image) != len(order) {
+			t.Fatalf("expected %d frames, got %d", len(order), len(butteryGif.Image))
+		}
+
+		for i, j := range order {
+			expected, actual := sourceGif.Image[j], butteryGif.Image[i]
+
+			// Passthrough preserves palette order and pixel indices.
+			if !slices.Equal(expected.Palette, actual.Palette) || !bytes.Equal(expected.Pix, actual.Pix) {
+				t.Errorf("expected memory limit %d frame %d identical to source frame %d", memoryLimit, i, j)
+			}
+
+			if butteryGif.Delay[i] != 6 {
+				t.Errorf("expected delay 6, got %d", butteryGif.Delay[i])
+			}
+		}
+	}
+}
+
+func TestConfigPipelineFades(t *testing.T) {
+	for _, tc := range []struct {
+		stitch, fade buttery.Stitch
+		expected     string
+	}{
+		{stitch: buttery.Mirror, fade: buttery.Fade, expected: "[Mirror Fade delay:1]"},
+		{stitch: buttery.None, fade: buttery.Fade.With("color", "0xffffff"), expected: "[None Fade:color=0xffffff delay:1]"},
+		{stitch: buttery.Fade, fade: buttery.Fade, expected: "[Fade delay:1]"},
+		{stitch: buttery.Shuffle, fade: buttery.Fade, expected: "[Shuffle delay:1]"},
+		{stitch: buttery.Mirror, fade: buttery.None, expected: "[Mirror delay:1]"},
+	} {
+		config := buttery.NewConfig()
+		config.Stitch, config.Fade = tc.stitch, tc.fade
+
+		if operations := fmt.Sprint(config.Pipeline().Operations); operations != tc.expected {
+			t.Errorf("expected %v stitch with %v fade to plan %v, got %v", tc.stitch, tc.fade, tc.expected, operations)
+		}
+	}
+}
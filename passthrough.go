@@ -0,0 +1,160 @@
+package buttery
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"sync"
+)
+
+// reordersOnly reports whether operations merely reorder frames or change timing,
+// leaving pixels untouched.
+//
+// Custom operations and stitches never qualify.
+func reordersOnly(operations []Operation) bool {
+	for _, operation := range operations {
+		switch op := operation.(type) {
+		case Reverse, Trim, Window, Cut, Shift, ScaleDelay:
+		case StitchStage:
+			if !op.Stitch.Is(None.Name) && !op.Stitch.Is(Mirror.Name) && !op.Stitch.Is(Shuffle.Name) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// standalone reports whether a source frame renders as itself,
+// by covering the whole logical screen
+// with opaque pixels or else atop a clear screen.
+func standalone(paletted *image.Paletted, screen image.Rectangle, overClear bool) bool {
+	if paletted.Rect != screen {
+		return false
+	}
+
+	if overClear {
+		return true
+	}
+
+	var used [256]bool
+	width := paletted.Rect.Dx()
+
+	for y := 0; y < paletted.Rect.Dy(); y++ {
+		for _, index := range paletted.Pix[y*paletted.Stride : y*paletted.Stride+width] {
+			used[index] = true
+		}
+	}
+
+	for index, ok := range used {
+		if !ok {
+			continue
+		}
+
+		if index >= len(paletted.Palette) {
+			return false
+		}
+
+		if _, _, _, a := paletted.Palette[index].RGBA(); a != 0xffff {
+			return false
+		}
+	}
+
+	return true
+}
+
+// encodedImage models a standalone source frame kept as its original GIF image block,
+// so that reordering edits copy LZW data verbatim.
+//
+// Pixel queries decode the block once, keeping the pixels in memory,
+// so bulk consumers should decode instead.
+type encodedImage struct {
+	// header holds the source signature, logical screen descriptor, and global color table.
+	header []byte
+
+	// block holds the image descriptor, any local color table, and LZW image data.
+	block []byte
+
+	// file, offset, and size locate a block spilled to a frameCache, in place of block.
+	file   *os.File
+	offset int64
+	size   int
+
+	// transparentIndex denotes the palette index rendered clear, or -1 for none.
+	transparentIndex int
+
+	rect    image.Rectangle
+	palette color.Palette
+
+	// once guards decoded, the pixels memoized for At.
+	once    sync.Once
+	decoded *image.Paletted
+}
+
+// readBlock queries the image block, reading back any spilled block.
+func (o *encodedImage) readBlock() ([]byte, error) {
+	if o.file == nil {
+		return o.block, nil
+	}
+
+	block := make([]byte, o.size)
+
+	if _, err := o.file.ReadAt(block, o.offset); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// decode expands the image block.
+func (o *encodedImage) decode() (*image.Paletted, error) {
+	block, err := o.readBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(o.header)
+
+	if o.transparentIndex >= 0 {
+		buf.Write([]byte{gifExtension, gifGraphicControl, 0x04, 0x01, 0x00, 0x00, byte(o.transparentIndex), 0x00})
+	}
+
+	buf.Write(block)
+	buf.WriteByte(gifTrailer)
+	frameGif, err := gif.DecodeAll(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return frameGif.Image[0], nil
+}
+
+// ColorModel queries the palette.
+func (o *encodedImage) ColorModel() color.Model {
+	return o.palette
+}
+
+// Bounds queries the image dimensions.
+func (o *encodedImage) Bounds() image.Rectangle {
+	return o.rect
+}
+
+// At queries the color of a pixel.
+func (o *encodedImage) At(x, y int) color.Color {
+	o.once.Do(func() {
+		if decoded, err := o.decode(); err == nil {
+			o.decoded = decoded
+		}
+	})
+
+	if o.decoded == nil {
+		return color.Transparent
+	}
+
+	return o.decoded.At(x, y)
+}
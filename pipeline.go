@@ -0,0 +1,491 @@
+package buttery
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"slices"
+
+	"github.com/andybons/gogif"
+)
+
+// Pipeline models an ordered sequence of animation editing stages.
+type Pipeline struct {
+	// Transparent preserves clear animations (Default false).
+	Transparent bool
+
+	// LoopCount denotes how many times to play the animation (Default 0).
+	//
+	// -1 indicates one play.
+	// 0 indicates infinite, endless plays.
+	// N indicates 1+N iterations.
+	LoopCount int
+
+	// Operations denotes the editing stages, applied in order.
+	Operations []Operation
+
+	// Workers denotes how many frames to process concurrently (Default zero).
+	//
+	// Zero indicates one worker per CPU.
+	// Output is identical regardless of worker count.
+	Workers int
+
+	// MemoryLimit caps the bytes of frames held in memory while streaming (Default zero).
+	//
+	// Zero indicates no streaming, editing entirely in memory.
+	// Positive values make EditTo and EditToContext
+	// decode, composite, transform, and encode frames incrementally,
+	// spilling frames beyond the limit to a temporary file.
+	// Frames kept as original image blocks count their encoded size.
+	// Working buffers for in flight frames come on top of the limit.
+	MemoryLimit int64
+}
+
+// Validate checks for basic Pipeline integrity.
+func (o Pipeline) Validate() error {
+	for _, operation := range o.Operations {
+		if err := operation.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EditTo decodes a GIF from the given reader,
+// applies the pipeline,
+// and encodes the result to the given writer.
+func (o Pipeline) EditTo(w io.Writer, r io.Reader) error {
+	return o.EditToContext(context.Background(), w, r)
+}
+
+// EditToContext decodes a GIF from the given reader,
+// applies the pipeline,
+// and encodes the result to the given writer,
+// reporting progress and honoring cancellation.
+func (o Pipeline) EditToContext(ctx context.Context, w io.Writer, r io.Reader) error {
+	if o.MemoryLimit > 0 {
+		return o.editStream(ctx, w, r)
+	}
+
+	sourceGif, err := gif.DecodeAll(r)
+	if err != nil {
+		return err
+	}
+
+	butteryGif, err := o.EditGIFContext(ctx, sourceGif)
+	if err != nil {
+		return err
+	}
+
+	return gif.EncodeAll(w, butteryGif)
+}
+
+// EditGIF applies the pipeline in memory.
+//
+// The source GIF is left unmodified.
+func (o Pipeline) EditGIF(sourceGif *gif.GIF) (*gif.GIF, error) {
+	return o.EditGIFContext(context.Background(), sourceGif)
+}
+
+// EditGIFContext applies the pipeline in memory,
+// reporting progress and honoring cancellation.
+//
+// The source GIF is left unmodified.
+func (o Pipeline) EditGIFContext(ctx context.Context, sourceGif *gif.GIF) (*gif.GIF, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := validateSource(sourceGif); err != nil {
+		return nil, err
+	}
+
+	ctx = WithWorkers(ctx, o.Workers)
+	sourceTimeline, err := composite(ctx, sourceGif, o.Transparent, reordersOnly(o.Operations))
+	if err != nil {
+		return nil, err
+	}
+
+	timeline, err := o.Apply(ctx, sourceTimeline)
+	if err != nil {
+		return nil, err
+	}
+
+	quantizer := gogif.MedianCutQuantizer{NumColor: max(GetPaletteSize(sourceGif.Image), 2)}
+	butteryGif := gif.GIF{
+		LoopCount:       o.LoopCount,
+		BackgroundIndex: sourceGif.BackgroundIndex,
+		Config:          sourceGif.Config,
+		Image:           make([]*image.Paletted, len(timeline)),
+		Delay:           timeline.Delays(),
+		Disposal:        make([]byte, len(timeline)),
+	}
+
+	if err := forEachFrame(ctx, StageEncode, len(timeline), func(i int) func() {
+		butteryGif.Disposal[i] = timeline[i].Disposal
+		return func() { butteryGif.Image[i] = quantize(&quantizer, timeline[i].Image) }
+	}); err != nil {
+		return nil, err
+	}
+
+	return &butteryGif, nil
+}
+
+// editStream applies the pipeline incrementally,
+// holding at most MemoryLimit bytes of frames in memory.
+func (o Pipeline) editStream(ctx context.Context, w io.Writer, r io.Reader) (err error) {
+	if err = o.Validate(); err != nil {
+		return err
+	}
+
+	reader, err := newFrameReader(r)
+	if err != nil {
+		return err
+	}
+
+	cache := newFrameCache(o.MemoryLimit)
+
+	defer func() {
+		err = errors.Join(err, cache.Close())
+	}()
+
+	ctx = withFrameCache(WithWorkers(ctx, o.Workers), cache)
+	sourceTimeline, paletteSize, err := compositeStream(ctx, reader, o.Transparent, reordersOnly(o.Operations))
+	if err != nil {
+		return err
+	}
+
+	timeline, err := o.Apply(ctx, sourceTimeline)
+	if err != nil {
+		return err
+	}
+
+	writer, err := newFrameWriter(w, reader.config, reader.backgroundIndex, o.LoopCount)
+	if err != nil {
+		return err
+	}
+
+	if err = encodeStream(ctx, writer, timeline, paletteSize); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// compositeStream flattens source frames onto the logical screen,
+// a batch of frames at a time, admitting each result to the frame cache.
+//
+// Quantization honors the largest palette seen so far,
+// returned alongside the timeline.
+//
+// With passthrough, standalone frames keep their original image blocks.
+func compositeStream(ctx context.Context, reader *frameReader, transparent, passthrough bool) (Timeline, int, error) {
+	cache := getFrameCache(ctx)
+	var timeline Timeline
+	var c *compositor
+	var paletteSize int
+
+	for eof := false; !eof; {
+		var sourceFrames []*sourceFrame
+
+		for len(sourceFrames) < Workers(ctx) {
+			source, err := reader.Next()
+
+			if errors.Is(err, io.EOF) {
+				eof = true
+				break
+			}
+
+			if err != nil {
+				return nil, 0, err
+			}
+
+			if len(source.paletted.Palette) == 0 {
+				return nil, 0, fmt.Errorf("frame %d: %w", len(timeline)+len(sourceFrames), ErrInvalidFrame)
+			}
+
+			sourceFrames = append(sourceFrames, source)
+			paletteSize = max(paletteSize, len(source.paletted.Palette))
+		}
+
+		if len(sourceFrames) == 0 {
+			break
+		}
+
+		if c == nil {
+			c = newCompositor(image.Rect(0, 0, reader.config.Width, reader.config.Height), sourceFrames[0].paletted, transparent)
+			c.passthrough = passthrough
+			c.header = reader.header
+		}
+
+		c.quantizer.NumColor = paletteSize
+		offset := len(timeline)
+		batch := make(Timeline, len(sourceFrames))
+		errs := make([]error, len(batch))
+
+		if err := forEachFrame(offsetProgress(ctx, offset, 0), StageComposite, len(batch), func(i int) func() {
+			job := c.prepare(sourceFrames[i], &batch[i])
+
+			return func() {
+				job()
+				batch[i].Image, errs[i] = cache.store(batch[i].Image)
+			}
+		}); err != nil {
+			return nil, 0, err
+		}
+
+		if err := errors.Join(errs...); err != nil {
+			return nil, 0, err
+		}
+
+		timeline = append(timeline, batch...)
+	}
+
+	if len(timeline) == 0 {
+		return nil, 0, ErrNoFrames
+	}
+
+	return timeline, paletteSize, nil
+}
+
+// encodeStream quantizes and writes frames in order, a batch of frames at a time.
+//
+// Frames holding original image blocks copy verbatim.
+func encodeStream(ctx context.Context, writer *frameWriter, timeline Timeline, paletteSize int) error {
+	cache := getFrameCache(ctx)
+	quantizer := gogif.MedianCutQuantizer{NumColor: max(paletteSize, 2)}
+	batchSize := Workers(ctx)
+
+	for offset := 0; offset < len(timeline); offset += batchSize {
+		batch := timeline[offset:min(offset+batchSize, len(timeline))]
+		paletteds := make([]*image.Paletted, len(batch))
+		errs := make([]error, len(batch))
+
+		if err := forEachFrame(offsetProgress(ctx, offset, len(timeline)), StageEncode, len(batch), func(i int) func() {
+			return func() {
+				if _, ok := batch[i].Image.(*encodedImage); ok {
+					return
+				}
+
+				img, err := cache.load(batch[i].Image)
+
+				if err != nil {
+					errs[i] = err
+					return
+				}
+
+				paletteds[i] = quantize(&quantizer, img)
+			}
+		}); err != nil {
+			return err
+		}
+
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+
+		for i, paletted := range paletteds {
+			var err error
+
+			if encoded, ok := batch[i].Image.(*encodedImage); ok {
+				var block []byte
+
+				if block, err = encoded.readBlock(); err == nil {
+					err = writer.WriteBlock(block, batch[i].Delay, batch[i].Disposal, encoded.transparentIndex)
+				}
+			} else {
+				err = writer.Write(paletted, batch[i].Delay, batch[i].Disposal)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateSource rejects GIFs that cannot be composited.
+func validateSource(sourceGif *gif.GIF) error {
+	if sourceGif == nil || len(sourceGif.Image) == 0 {
+		return ErrNoFrames
+	}
+
+	var errs []error
+
+	for i, paletted := range sourceGif.Image {
+		if paletted == nil || len(paletted.Palette) == 0 {
+			errs = append(errs, fmt.Errorf("frame %d: %w", i, ErrInvalidFrame))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Apply runs each operation in order.
+func (o Pipeline) Apply(ctx context.Context, timeline Timeline) (Timeline, error) {
+	for _, operation := range o.Operations {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var err error
+		timeline, err = operation.Apply(ctx, timeline)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return timeline, nil
+}
+
+// quantize reduces an image to a GIF compatible palette.
+//
+// Paletted images pass through unmodified.
+func quantize(quantizer *gogif.MedianCutQuantizer, img image.Image) *image.Paletted {
+	if paletted, ok := img.(*image.Paletted); ok {
+		return paletted
+	}
+
+	bounds := img.Bounds()
+	paletted := image.NewPaletted(bounds, nil)
+	quantizer.Quantize(paletted, bounds, img, bounds.Min)
+	sortPalette(paletted)
+	return paletted
+}
+
+// sortPalette reorders a palette by color value, remapping pixel indices.
+//
+// MedianCutQuantizer may emit palettes in random map order,
+// so sorting keeps encoded output reproducible.
+func sortPalette(paletted *image.Paletted) {
+	palette := paletted.Palette
+	order := make([]int, len(palette))
+
+	for i := range order {
+		order[i] = i
+	}
+
+	key := func(i int) uint64 {
+		r, g, b, a := palette[i].RGBA()
+		return uint64(r)<<48 | uint64(g)<<32 | uint64(b)<<16 | uint64(a)
+	}
+
+	slices.SortStableFunc(order, func(i, j int) int { return cmp.Compare(key(i), key(j)) })
+	sorted := make(color.Palette, len(palette))
+	remap := make([]uint8, len(palette))
+
+	for newIndex, oldIndex := range order {
+		sorted[newIndex] = palette[oldIndex]
+		remap[oldIndex] = uint8(newIndex)
+	}
+
+	for i, index := range paletted.Pix {
+		if int(index) < len(remap) {
+			paletted.Pix[i] = remap[index]
+		}
+	}
+
+	paletted.Palette = sorted
+}
+
+// composite flattens each source frame onto the logical screen,
+// honoring source disposal methods.
+//
+// With passthrough, standalone frames keep their original palettes and pixels.
+func composite(ctx context.Context, sourceGif *gif.GIF, transparent, passthrough bool) (Timeline, error) {
+	sourcePaletteds := sourceGif.Image
+	c := newCompositor(screenBounds(sourceGif), sourcePaletteds[0], transparent)
+	c.passthrough = passthrough
+	c.quantizer.NumColor = GetPaletteSize(sourcePaletteds)
+	timeline := make(Timeline, len(sourcePaletteds))
+
+	if err := forEachFrame(ctx, StageComposite, len(sourcePaletteds), func(i int) func() {
+		var delay int
+
+		if i < len(sourceGif.Delay) {
+			delay = sourceGif.Delay[i]
+		}
+
+		return c.prepare(&sourceFrame{paletted: sourcePaletteds[i], delay: delay, disposal: disposalAt(sourceGif, i)}, &timeline[i])
+	}); err != nil {
+		return nil, err
+	}
+
+	return timeline, nil
+}
+
+// compositor flattens source frames onto a running logical screen.
+type compositor struct {
+	renderer    *renderer
+	transparent bool
+	disposal    byte
+	quantizer   gogif.MedianCutQuantizer
+
+	// passthrough reuses standalone source frames rather than quantizing renders.
+	passthrough bool
+
+	// header holds the source GIF header for reusing original image blocks.
+	header []byte
+}
+
+// newCompositor generates a compositor with a screen of the given bounds.
+//
+// Transparent screens begin clear.
+// Opaque screens begin with the color of the first frame's palette nearest to black.
+func newCompositor(bounds image.Rectangle, first *image.Paletted, transparent bool) *compositor {
+	disposal := byte(gif.DisposalNone)
+	background := first.Palette.Convert(color.Alpha16{0})
+
+	if transparent {
+		// Each output frame replaces the whole screen.
+		disposal = byte(gif.DisposalBackground)
+		background = color.Transparent
+	}
+
+	return &compositor{renderer: newRenderer(bounds, background), transparent: transparent, disposal: disposal}
+}
+
+// prepare renders a source frame,
+// returning a job that quantizes the result into the given frame.
+func (o *compositor) prepare(source *sourceFrame, frame *Frame) func() {
+	sourcePaletted, delay := source.paletted, source.delay
+
+	// Only transparent screens clear each output frame.
+	overClear := o.transparent && o.renderer.clear
+	snapshot := o.renderer.render(sourcePaletted, source.disposal)
+	bounds := snapshot.Bounds()
+
+	if o.passthrough && standalone(sourcePaletted, bounds, overClear) {
+		var img image.Image = sourcePaletted
+
+		if source.block != nil {
+			img = &encodedImage{
+				header:           o.header,
+				block:            source.block,
+				transparentIndex: source.transparentIndex,
+				rect:             sourcePaletted.Rect,
+				palette:          sourcePaletted.Palette,
+			}
+		}
+
+		return func() { *frame = Frame{Image: img, Delay: delay, Disposal: o.disposal} }
+	}
+
+	quantizer := o.quantizer
+
+	return func() {
+		clonePaletted := image.NewPaletted(bounds, sourcePaletted.Palette)
+		quantizer.Quantize(clonePaletted, bounds, snapshot, bounds.Min)
+		sortPalette(clonePaletted)
+		*frame = Frame{Image: clonePaletted, Delay: delay, Disposal: o.disposal}
+	}
+}
@@ -0,0 +1,122 @@
+package buttery
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"slices"
+	"strings"
+)
+
+// PlannedFrame models an output frame in terms of the source frame it derives from.
+type PlannedFrame struct {
+	// Source denotes the source frame index,
+	// or -1 when a custom stitch or operation generates new images.
+	Source int `json:"source"`
+
+	// Effects lists the pixel transformations applied to the source frame, in order,
+	// such as fliph, flipv, pan(dx,dy), and fade(amount).
+	Effects []string `json:"effects,omitempty"`
+
+	// Delay denotes the frame duration in centisec.
+	Delay int `json:"delay"`
+}
+
+// String renders the planned frame, such as 2 or 2:fliph.
+func (o PlannedFrame) String() string {
+	source := "?"
+
+	if o.Source >= 0 {
+		source = fmt.Sprint(o.Source)
+	}
+
+	if len(o.Effects) == 0 {
+		return source
+	}
+
+	return source + ":" + strings.Join(o.Effects, ":")
+}
+
+// planImage stands in for frame images while planning,
+// recording transformations rather than applying them.
+type planImage struct {
+	source  int
+	effects []string
+	rect    image.Rectangle
+}
+
+// with records a further transformation.
+func (o *planImage) with(effect string) *planImage {
+	return &planImage{source: o.source, effects: append(slices.Clone(o.effects), effect), rect: o.rect}
+}
+
+// ColorModel queries the color model.
+func (o *planImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+// Bounds queries the image dimensions.
+func (o *planImage) Bounds() image.Rectangle {
+	return o.rect
+}
+
+// At queries the color of a pixel, always clear.
+func (o *planImage) At(_, _ int) color.Color {
+	return color.Transparent
+}
+
+// Plan maps each output frame of the pipeline to its source frame,
+// without rendering, transforming, or encoding any pixels.
+//
+// Shuffle plans a single random ordering.
+func (o Pipeline) Plan(sourceGif *gif.GIF) ([]PlannedFrame, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := validateSource(sourceGif); err != nil {
+		return nil, err
+	}
+
+	screen := screenBounds(sourceGif)
+	timeline := make(Timeline, len(sourceGif.Image))
+
+	for i := range timeline {
+		timeline[i] = Frame{Image: &planImage{source: i, rect: screen}}
+
+		if i < len(sourceGif.Delay) {
+			timeline[i].Delay = sourceGif.Delay[i]
+		}
+	}
+
+	timeline, err := o.Apply(WithWorkers(context.Background(), o.Workers), timeline)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make([]PlannedFrame, len(timeline))
+
+	for i, frame := range timeline {
+		plan[i] = PlannedFrame{Source: -1, Delay: frame.Delay}
+
+		if img, ok := frame.Image.(*planImage); ok {
+			plan[i].Source, plan[i].Effects = img.source, img.effects
+		}
+	}
+
+	return plan, nil
+}
+
+// Plan maps each output frame of the configured edits to its source frame,
+// without rendering, transforming, or encoding any pixels.
+//
+// Shuffle plans a single random ordering.
+func (o *Config) Plan(sourceGif *gif.GIF) ([]PlannedFrame, error) {
+	if err := o.ValidateFor(sourceGif); err != nil {
+		return nil, err
+	}
+
+	return o.Pipeline().Plan(sourceGif)
+}
@@ -0,0 +1,68 @@
+package buttery_test
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"reflect"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+func TestPlan(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	sourceGif := &gif.GIF{
+		Image: []*image.Paletted{
+			image.NewPaletted(image.Rect(0, 0, 4, 4), palette),
+			image.NewPaletted(image.Rect(0, 0, 4, 4), palette),
+			image.NewPaletted(image.Rect(0, 0, 4, 4), palette),
+			image.NewPaletted(image.Rect(0, 0, 4, 4), palette),
+		},
+		Delay:  []int{2, 3, 4, 5},
+		Config: image.Config{Width: 4, Height: 4},
+	}
+
+	config := buttery.NewConfig()
+	config.TrimStart = 1
+	plan, err := config.Plan(sourceGif)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []buttery.PlannedFrame{
+		{Source: 1, Effects: []string{"fade(1.00)"}, Delay: 3},
+		{Source: 2, Effects: []string{"fade(0.75)"}, Delay: 4},
+		{Source: 3, Effects: []string{"fade(0.50)"}, Delay: 5},
+		{Source: 2, Effects: []string{"fade(0.50)"}, Delay: 4},
+		{Source: 1, Effects: []string{"fade(0.75)"}, Delay: 3},
+	}
+
+	if !reflect.DeepEqual(plan, expected) {
+		t.Errorf("expected faded mirror plan %v, got %v", expected, plan)
+	}
+
+	operations, err := buttery.ParseOperations("window:2|fliph|panv:3|delay:2")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err = buttery.Pipeline{Operations: operations}.Plan(sourceGif)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = []buttery.PlannedFrame{
+		{Source: 0, Delay: 4},
+		{Source: 1, Effects: []string{"pan(0,3)"}, Delay: 6},
+		{Source: 0, Effects: []string{"fliph", "pan(0,2)"}, Delay: 4},
+		{Source: 1, Effects: []string{"fliph", "pan(0,1)"}, Delay: 6},
+	}
+
+	if !reflect.DeepEqual(plan, expected) {
+		t.Errorf("expected flip and pan plan %v, got %v", expected, plan)
+	}
+}
@@ -0,0 +1,76 @@
+package buttery
+
+import (
+	"context"
+)
+
+// Stage labels a phase of editing.
+type Stage string
+
+const (
+	// StageComposite flattens source frames onto full canvases.
+	StageComposite Stage = "composite"
+
+	// StageStitch applies loop continuity transitions.
+	StageStitch Stage = "stitch"
+
+	// StageFade applies time color gradients.
+	StageFade Stage = "fade"
+
+	// StageEncode reduces frames to GIF palettes.
+	StageEncode Stage = "encode"
+)
+
+// Progress models a report of editing progress.
+type Progress struct {
+	// Stage denotes the current phase of editing.
+	Stage Stage
+
+	// Frame denotes the zero-based index of the frame just processed.
+	//
+	// Frames processed concurrently may report out of order.
+	Frame int
+
+	// Done denotes how many frames of the stage have completed.
+	Done int
+
+	// Frames denotes the total frame count for the stage.
+	//
+	// Zero indicates a total not yet known, such as while streaming source frames.
+	Frames int
+}
+
+// ProgressFunc receives progress reports.
+//
+// Reports arrive synchronously, one at a time, so callbacks should return promptly.
+type ProgressFunc func(progress Progress)
+
+// progressKey indexes a ProgressFunc within a context.
+type progressKey struct{}
+
+// WithProgress generates a context that delivers progress reports to the given callback.
+func WithProgress(ctx context.Context, f ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, f)
+}
+
+// offsetProgress generates a context that reports progress of a batch
+// relative to an entire stage, beginning at the given frame offset.
+//
+// Zero frames indicates a stage total not yet known.
+func offsetProgress(ctx context.Context, offset, frames int) context.Context {
+	return WithProgress(ctx, func(progress Progress) {
+		progress.Frame += offset
+		progress.Done += offset
+		progress.Frames = frames
+		ReportProgress(ctx, progress)
+	})
+}
+
+// ReportProgress delivers a progress report to any callback registered with WithProgress.
+//
+// Custom Operations and Stitchers may report their own progress.
+func ReportProgress(ctx context.Context, progress Progress) {
+	if f, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && f != nil {
+		f(progress)
+	}
+}
@@ -0,0 +1,112 @@
+package buttery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Recipe models a reproducible edit,
+// recording a Config alongside the source it applied to.
+//
+// Recipes encode as TOML or JSON, with keys named after Config fields.
+type Recipe struct {
+	Config
+
+	// Operations overrides the Config's editing stages when nonempty,
+	// in ParseOperations syntax.
+	Operations string `toml:",omitempty" json:",omitempty"`
+
+	// Input names the source GIF.
+	Input string `toml:",omitempty" json:",omitempty"`
+
+	// InputSHA256 holds the hex SHA-256 digest of the source GIF.
+	InputSHA256 string `toml:",omitempty" json:",omitempty"`
+
+	// Version denotes the buttery version that wrote the recipe.
+	Version string `toml:",omitempty" json:",omitempty"`
+}
+
+// NewRecipe generates a Recipe with a default Config.
+func NewRecipe() Recipe {
+	return Recipe{Config: NewConfig()}
+}
+
+// LoadRecipe reads a recipe file, JSON for a .json extension and TOML otherwise.
+//
+// Omitted keys retain their NewConfig defaults. Unknown keys are rejected.
+func LoadRecipe(pth string) (recipe Recipe, err error) {
+	f, err := os.Open(pth)
+	if err != nil {
+		return Recipe{}, err
+	}
+
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+
+	if strings.EqualFold(filepath.Ext(pth), ".json") {
+		return DecodeRecipeJSON(f)
+	}
+
+	return DecodeRecipeTOML(f)
+}
+
+// DecodeRecipeTOML reads a TOML recipe.
+//
+// Omitted keys retain their NewConfig defaults. Unknown keys are rejected.
+func DecodeRecipeTOML(r io.Reader) (Recipe, error) {
+	recipe := NewRecipe()
+	metadata, err := toml.NewDecoder(r).Decode(&recipe)
+	if err != nil {
+		return Recipe{}, err
+	}
+
+	if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+		return Recipe{}, fmt.Errorf("unknown recipe key: %v", undecoded[0])
+	}
+
+	return recipe, nil
+}
+
+// DecodeRecipeJSON reads a JSON recipe.
+//
+// Omitted keys retain their NewConfig defaults. Unknown keys are rejected.
+func DecodeRecipeJSON(r io.Reader) (Recipe, error) {
+	recipe := NewRecipe()
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&recipe); err != nil {
+		return Recipe{}, err
+	}
+
+	return recipe, nil
+}
+
+// EncodeTOML writes the recipe as TOML, recording every Config field.
+func (o Recipe) EncodeTOML(w io.Writer) error {
+	return toml.NewEncoder(w).Encode(o)
+}
+
+// Pipeline generates the editing stages modeled by the recipe.
+func (o Recipe) Pipeline() (Pipeline, error) {
+	pipeline := o.Config.Pipeline()
+
+	if o.Operations != "" {
+		operations, err := ParseOperations(o.Operations)
+		if err != nil {
+			return Pipeline{}, err
+		}
+
+		pipeline.Operations = operations
+	}
+
+	return pipeline, nil
+}
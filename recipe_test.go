@@ -0,0 +1,60 @@
+package buttery_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+func TestRecipeRoundTrip(t *testing.T) {
+	recipe := buttery.NewRecipe()
+	recipe.TrimStart = 2
+	recipe.Shift = 3
+	recipe.Stitch = buttery.Fade.With("color", "0xffffff").With("rate", "0.5")
+	recipe.ScaleDelay = 1.5
+	recipe.Operations = "trim:2,0|fade:0xffffff"
+	recipe.InputSHA256 = "d4b6"
+	recipe.Version = buttery.Version
+
+	var buf bytes.Buffer
+
+	if err := recipe.EncodeTOML(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	recipe2, err := buttery.DecodeRecipeTOML(&buf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(recipe2, recipe) {
+		t.Errorf("expected symmetric recipe marshaling, got %+v", recipe2)
+	}
+}
+
+func TestRecipeDefaults(t *testing.T) {
+	recipe, err := buttery.DecodeRecipeJSON(strings.NewReader(`{"TrimStart": 2}`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := buttery.NewRecipe()
+	expected.TrimStart = 2
+
+	if !reflect.DeepEqual(recipe, expected) {
+		t.Errorf("expected omitted keys to retain defaults, got %+v", recipe)
+	}
+
+	if _, err2 := buttery.DecodeRecipeTOML(strings.NewReader("TrimStrat = 2\n")); err2 == nil {
+		t.Errorf("expected unknown TOML keys to be rejected")
+	}
+
+	if _, err2 := buttery.DecodeRecipeJSON(strings.NewReader(`{"TrimStrat": 2}`)); err2 == nil {
+		t.Errorf("expected unknown JSON keys to be rejected")
+	}
+}
@@ -0,0 +1,114 @@
+package buttery
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+)
+
+// Render composites each frame of a GIF onto its logical screen,
+// honoring each frame's disposal method.
+//
+// The screen begins transparent,
+// and DisposalBackground clears the frame area back to transparent,
+// matching common browser behavior.
+//
+// GIFs lacking a logical screen size render onto the union of their frame bounds.
+func Render(sourceGif *gif.GIF) []*image.RGBA {
+	if sourceGif == nil {
+		return nil
+	}
+
+	r := newRenderer(screenBounds(sourceGif), color.Transparent)
+	images := make([]*image.RGBA, len(sourceGif.Image))
+
+	for i, paletted := range sourceGif.Image {
+		images[i] = r.render(paletted, disposalAt(sourceGif, i))
+	}
+
+	return images
+}
+
+// screenBounds queries the logical screen of a GIF,
+// falling back to the union of frame bounds from the origin.
+func screenBounds(sourceGif *gif.GIF) image.Rectangle {
+	if sourceGif.Config.Width > 0 && sourceGif.Config.Height > 0 {
+		return image.Rect(0, 0, sourceGif.Config.Width, sourceGif.Config.Height)
+	}
+
+	// Union skips empty rectangles, so grow from the origin by hand.
+	var bounds image.Rectangle
+
+	for _, paletted := range sourceGif.Image {
+		if paletted != nil {
+			r := paletted.Rect
+			bounds = image.Rect(min(bounds.Min.X, r.Min.X), min(bounds.Min.Y, r.Min.Y), max(bounds.Max.X, r.Max.X), max(bounds.Max.Y, r.Max.Y))
+		}
+	}
+
+	return bounds
+}
+
+// disposalAt queries the disposal method of a frame, defaulting to unspecified.
+func disposalAt(sourceGif *gif.GIF, i int) byte {
+	if i < len(sourceGif.Disposal) {
+		return sourceGif.Disposal[i]
+	}
+
+	return 0
+}
+
+// renderer composites frames onto a running logical screen.
+type renderer struct {
+	canvas     *image.RGBA
+	background *image.Uniform
+
+	// clear reports whether the screen holds only background.
+	clear bool
+}
+
+// newRenderer generates a renderer with a screen of the given bounds,
+// cleared to the given background color.
+func newRenderer(bounds image.Rectangle, background color.Color) *renderer {
+	r := &renderer{canvas: image.NewRGBA(bounds), background: image.NewUniform(background), clear: true}
+	draw.Src.Draw(r.canvas, bounds, r.background, image.Point{})
+	return r
+}
+
+// render draws a frame onto the screen,
+// returning a snapshot of the screen before applying the frame's disposal method.
+func (o *renderer) render(paletted *image.Paletted, disposal byte) *image.RGBA {
+	var area image.Rectangle
+	var previous *image.RGBA
+
+	if disposal == gif.DisposalPrevious {
+		previous = cloneRGBA(o.canvas)
+	}
+
+	if paletted != nil {
+		area = paletted.Rect.Intersect(o.canvas.Bounds())
+		draw.Over.Draw(o.canvas, area, paletted, area.Min)
+	}
+
+	snapshot := cloneRGBA(o.canvas)
+
+	switch disposal {
+	case gif.DisposalBackground:
+		draw.Src.Draw(o.canvas, area, o.background, image.Point{})
+		o.clear = o.clear || area == o.canvas.Bounds()
+	case gif.DisposalPrevious:
+		o.canvas = previous
+	default:
+		o.clear = o.clear && paletted == nil
+	}
+
+	return snapshot
+}
+
+// cloneRGBA copies an RGBA image.
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	clone := image.NewRGBA(img.Rect)
+	copy(clone.Pix, img.Pix)
+	return clone
+}
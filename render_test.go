@@ -0,0 +1,79 @@
+package buttery_test
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+func TestRenderDisposal(t *testing.T) {
+	red := color.RGBA{R: 0xFF, A: 0xFF}
+	green := color.RGBA{G: 0xFF, A: 0xFF}
+	blue := color.RGBA{B: 0xFF, A: 0xFF}
+	none := color.RGBA{}
+	palette := color.Palette{red, green, blue, color.White}
+	frame := func(x0, x1 int, index uint8) *image.Paletted {
+		paletted := image.NewPaletted(image.Rect(x0, 0, x1, 1), palette)
+
+		for i := range paletted.Pix {
+			paletted.Pix[i] = index
+		}
+
+		return paletted
+	}
+
+	sourceGif := &gif.GIF{
+		Image:    []*image.Paletted{frame(0, 4, 0), frame(1, 2, 2), frame(2, 3, 1), frame(3, 4, 3)},
+		Delay:    []int{2, 2, 2, 2},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalPrevious, gif.DisposalBackground, gif.DisposalNone},
+		Config:   image.Config{Width: 5, Height: 1},
+	}
+
+	expected := [][]color.RGBA{
+		{red, red, red, red, none},
+		{red, blue, red, red, none},
+		{red, red, green, red, none},
+		{red, red, none, color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}, none},
+	}
+
+	images := buttery.Render(sourceGif)
+
+	if len(images) != len(expected) {
+		t.Fatalf("expected %d images, got %d", len(expected), len(images))
+	}
+
+	for i, img := range images {
+		if img.Bounds() != image.Rect(0, 0, 5, 1) {
+			t.Errorf("expected frame %d to span the logical screen, got %v", i, img.Bounds())
+		}
+
+		for x, c := range expected[i] {
+			if c2 := img.RGBAAt(x, 0); c2 != c {
+				t.Errorf("expected frame %d pixel %d %v, got %v", i, x, c, c2)
+			}
+		}
+	}
+}
+
+func TestGetDimensionsMatchesRender(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	paletteds := []*image.Paletted{
+		image.NewPaletted(image.Rect(2, 1, 5, 4), palette),
+		image.NewPaletted(image.Rect(3, 2, 7, 3), palette),
+	}
+
+	width, height := buttery.GetDimensions(paletteds)
+	bounds := buttery.Render(&gif.GIF{Image: paletteds, Delay: []int{1, 1}})[0].Bounds()
+
+	if width != bounds.Dx() || height != bounds.Dy() {
+		t.Errorf("expected dimensions %dx%d, got %dx%d", bounds.Dx(), bounds.Dy(), width, height)
+	}
+
+	// Offset frames measure from the origin.
+	if width, height := buttery.GetDimensions([]*image.Paletted{image.NewPaletted(image.Rect(10, 10, 20, 20), palette)}); width != 20 || height != 20 {
+		t.Errorf("expected dimensions 20x20 from the origin, got %dx%d", width, height)
+	}
+}
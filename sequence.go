@@ -0,0 +1,236 @@
+package buttery
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	_ "image/jpeg" // Register JPEG decoding for ImportFrames.
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ManifestName names the delays manifest of a frame sequence directory.
+const ManifestName = "frames.json"
+
+// DefaultImportDelay denotes the delay in centisec of imported frames absent from the manifest.
+const DefaultImportDelay = 10
+
+// FrameManifest models the timing of a frame sequence directory.
+type FrameManifest struct {
+	// LoopCount follows gif.GIF.LoopCount.
+	LoopCount int `json:"loopCount"`
+
+	// Frames lists the frame files in order.
+	Frames []ManifestFrame `json:"frames"`
+}
+
+// ManifestFrame models the timing of a frame file.
+type ManifestFrame struct {
+	// File names the image file, relative to the sequence directory.
+	File string `json:"file"`
+
+	// Delay denotes the frame duration in centisec.
+	Delay int `json:"delay"`
+}
+
+// ExportFrames renders each frame of a GIF, honoring disposal methods,
+// as numbered PNG files in a directory, along with a delays manifest.
+//
+// The directory is created as needed.
+func ExportFrames(dir string, sourceGif *gif.GIF) error {
+	if err := validateSource(sourceGif); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	images := Render(sourceGif)
+	digits := max(4, len(fmt.Sprint(len(images))))
+	manifest := FrameManifest{LoopCount: sourceGif.LoopCount, Frames: make([]ManifestFrame, len(images))}
+
+	for i, img := range images {
+		name := fmt.Sprintf("frame%0*d.png", digits, i+1)
+		manifest.Frames[i] = ManifestFrame{File: name}
+
+		if i < len(sourceGif.Delay) {
+			manifest.Frames[i].Delay = sourceGif.Delay[i]
+		}
+
+		if err := writePNG(filepath.Join(dir, name), img); err != nil {
+			return err
+		}
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(filepath.Join(dir, ManifestName), func(w io.Writer) error {
+		_, err2 := w.Write(append(manifestJSON, '\n'))
+		return err2
+	})
+}
+
+// writePNG encodes an image as a PNG file.
+func writePNG(pth string, img image.Image) error {
+	return WriteFileAtomic(pth, func(w io.Writer) error {
+		return png.Encode(w, img)
+	})
+}
+
+// ImportFrames assembles a GIF from the PNG and JPEG files of a directory,
+// see ImportAnimation.
+//
+// Each image renders as a whole frame, see Animation.GIF.
+func ImportFrames(dir string) (*gif.GIF, error) {
+	animation, err := ImportAnimation(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return animation.GIF()
+}
+
+// ImportAnimation assembles a truecolor animation from the PNG and JPEG files of a directory,
+// ordered by natural sort, such that frame2.png precedes frame10.png.
+//
+// Delays and loop count come from any manifest in the directory, see ExportFrames.
+// Files absent from the manifest receive DefaultImportDelay.
+func ImportAnimation(dir string) (*Animation, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".png", ".jpg", ".jpeg":
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, ErrNoFrames
+	}
+
+	slices.SortFunc(names, compareNatural)
+
+	manifest := FrameManifest{}
+	delays := make(map[string]int)
+	manifestJSON, err := os.ReadFile(filepath.Join(dir, ManifestName))
+
+	switch {
+	case err == nil:
+		if err2 := json.Unmarshal(manifestJSON, &manifest); err2 != nil {
+			return nil, fmt.Errorf("%v: %w", ManifestName, err2)
+		}
+
+		for _, frame := range manifest.Frames {
+			delays[frame.File] = frame.Delay
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	timeline := make(Timeline, len(names))
+
+	for i, name := range names {
+		img, err2 := readImage(filepath.Join(dir, name))
+		if err2 != nil {
+			return nil, fmt.Errorf("%v: %w", name, err2)
+		}
+
+		delay, ok := delays[name]
+
+		if !ok {
+			delay = DefaultImportDelay
+		}
+
+		timeline[i] = Frame{Image: img, Delay: delay}
+	}
+
+	return &Animation{Timeline: timeline, LoopCount: manifest.LoopCount}, nil
+}
+
+// readImage decodes an image file.
+func readImage(pth string) (img image.Image, err error) {
+	f, err := os.Open(pth)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+
+	img, _, err = image.Decode(f)
+	return img, err
+}
+
+// compareNatural orders strings with embedded numbers by numeric value,
+// such that frame2 precedes frame10.
+//
+// Ties, such as frame01 versus frame1, fall back to plain string order.
+func compareNatural(a, b string) int {
+	if c := compareNaturalRuns(a, b); c != 0 {
+		return c
+	}
+
+	return strings.Compare(a, b)
+}
+
+// compareNaturalRuns compares strings run by run, with digit runs compared numerically.
+func compareNaturalRuns(a, b string) int {
+	for a != "" && b != "" {
+		aDigits, bDigits := leadingDigits(a), leadingDigits(b)
+
+		if aDigits != "" && bDigits != "" {
+			aTrimmed, bTrimmed := strings.TrimLeft(aDigits, "0"), strings.TrimLeft(bDigits, "0")
+
+			if c := cmp.Compare(len(aTrimmed), len(bTrimmed)); c != 0 {
+				return c
+			}
+
+			if c := strings.Compare(aTrimmed, bTrimmed); c != 0 {
+				return c
+			}
+
+			a, b = a[len(aDigits):], b[len(bDigits):]
+			continue
+		}
+
+		if a[0] != b[0] {
+			return cmp.Compare(a[0], b[0])
+		}
+
+		a, b = a[1:], b[1:]
+	}
+
+	return cmp.Compare(len(a), len(b))
+}
+
+// leadingDigits queries the run of ASCII digits at the start of a string.
+func leadingDigits(s string) string {
+	end := strings.IndexFunc(s, func(r rune) bool { return r > unicode.MaxASCII || !unicode.IsDigit(r) })
+
+	if end < 0 {
+		return s
+	}
+
+	return s[:end]
+}
@@ -0,0 +1,101 @@
+package buttery_test
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+func TestFramesRoundTrip(t *testing.T) {
+	red := color.RGBA{R: 0xFF, A: 0xFF}
+	blue := color.RGBA{B: 0xFF, A: 0xFF}
+	palette := color.Palette{red, blue}
+	first := image.NewPaletted(image.Rect(0, 0, 3, 2), palette)
+	second := image.NewPaletted(image.Rect(1, 0, 2, 1), palette)
+	second.Pix[0] = 1
+	sourceGif := &gif.GIF{
+		Image:     []*image.Paletted{first, second},
+		Delay:     []int{4, 6},
+		Disposal:  []byte{gif.DisposalNone, gif.DisposalNone},
+		LoopCount: 2,
+		Config:    image.Config{Width: 3, Height: 2},
+	}
+
+	dir := t.TempDir()
+
+	if err := buttery.ExportFrames(dir, sourceGif); err != nil {
+		t.Fatal(err)
+	}
+
+	importGif, err := buttery.ImportFrames(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(importGif.Delay, sourceGif.Delay) || importGif.LoopCount != sourceGif.LoopCount {
+		t.Errorf("expected delays %v and loop count %d, got %v and %d", sourceGif.Delay, sourceGif.LoopCount, importGif.Delay, importGif.LoopCount)
+	}
+
+	expected, actual := buttery.Render(sourceGif), buttery.Render(importGif)
+
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d frames, got %d", len(expected), len(actual))
+	}
+
+	for i := range expected {
+		if !slices.Equal(actual[i].Pix, expected[i].Pix) {
+			t.Errorf("expected frame %d to round trip", i)
+		}
+	}
+}
+
+func TestImportFramesNaturalOrder(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{"frame10.png", "frame2.png", "frame1.png"}
+
+	for i, name := range names {
+		img := image.NewGray(image.Rect(0, 0, 1, 1))
+		img.Pix[0] = uint8(10 * (i + 1))
+		f, err := os.Create(filepath.Join(dir, name))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	importGif, err := buttery.ImportFrames(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var grays []uint8
+
+	for _, paletted := range importGif.Image {
+		grays = append(grays, color.GrayModel.Convert(paletted.At(0, 0)).(color.Gray).Y)
+	}
+
+	if expected := []uint8{30, 20, 10}; !slices.Equal(grays, expected) {
+		t.Errorf("expected natural order grays %v, got %v", expected, grays)
+	}
+
+	if expected := []int{10, 10, 10}; !slices.Equal(importGif.Delay, expected) {
+		t.Errorf("expected default delays %v, got %v", expected, importGif.Delay)
+	}
+}
@@ -0,0 +1,321 @@
+package buttery
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"image"
+	"image/draw"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SpriteSheet models the JSON metadata of a sprite sheet,
+// locating each frame within the sheet image.
+type SpriteSheet struct {
+	// Image names the sheet image file, relative to the metadata.
+	Image string `json:"image"`
+
+	// Width denotes the sheet width in pixels.
+	Width int `json:"width"`
+
+	// Height denotes the sheet height in pixels.
+	Height int `json:"height"`
+
+	// LoopCount follows gif.GIF.LoopCount.
+	LoopCount int `json:"loopCount"`
+
+	// Frames lists the frame areas in order.
+	Frames []SpriteFrame `json:"frames"`
+}
+
+// SpriteFrame models the area and timing of a frame within a sprite sheet.
+type SpriteFrame struct {
+	// X denotes the left edge of the frame in pixels.
+	X int `json:"x"`
+
+	// Y denotes the top edge of the frame in pixels.
+	Y int `json:"y"`
+
+	// Width denotes the frame width in pixels.
+	Width int `json:"width"`
+
+	// Height denotes the frame height in pixels.
+	Height int `json:"height"`
+
+	// Delay denotes the frame duration in centisec.
+	Delay int `json:"delay"`
+}
+
+// Rect queries the frame area within the sheet.
+func (o SpriteFrame) Rect() image.Rectangle {
+	return image.Rect(o.X, o.Y, o.X+o.Width, o.Y+o.Height)
+}
+
+// SpriteSidecar names a companion file of a sprite sheet image,
+// such as the <sheet>.json metadata.
+func SpriteSidecar(pth, ext string) string {
+	return strings.TrimSuffix(pth, filepath.Ext(pth)) + ext
+}
+
+// NewSpriteSheet tiles the frames of an animation into a grid image,
+// left to right and top to bottom, along with metadata locating each frame.
+//
+// Zero columns indicates a roughly square grid.
+func NewSpriteSheet(animation *Animation, columns int) (*image.NRGBA, SpriteSheet, error) {
+	if len(animation.Timeline) == 0 {
+		return nil, SpriteSheet{}, ErrNoFrames
+	}
+
+	if columns < 0 {
+		return nil, SpriteSheet{}, fmt.Errorf("columns %w", ErrNegative)
+	}
+
+	if columns == 0 {
+		columns = int(math.Ceil(math.Sqrt(float64(len(animation.Timeline)))))
+	}
+
+	columns = min(columns, len(animation.Timeline))
+	rows := (len(animation.Timeline) + columns - 1) / columns
+	bounds := animation.Bounds()
+	cell := bounds.Size()
+	sheetImage := image.NewNRGBA(image.Rect(0, 0, columns*cell.X, rows*cell.Y))
+	sheet := SpriteSheet{
+		Width:     sheetImage.Rect.Dx(),
+		Height:    sheetImage.Rect.Dy(),
+		LoopCount: animation.LoopCount,
+		Frames:    make([]SpriteFrame, len(animation.Timeline)),
+	}
+
+	for i, frame := range animation.Timeline {
+		if frame.Image == nil {
+			return nil, SpriteSheet{}, fmt.Errorf("frame %d: %w", i, ErrInvalidFrame)
+		}
+
+		x, y := i%columns*cell.X, i/columns*cell.Y
+		sheet.Frames[i] = SpriteFrame{X: x, Y: y, Width: cell.X, Height: cell.Y, Delay: frame.Delay}
+		draw.Draw(sheetImage, sheet.Frames[i].Rect(), toNRGBA(frame.Image, bounds), bounds.Min, draw.Src)
+	}
+
+	return sheetImage, sheet, nil
+}
+
+// NewSpriteGrid lays out the metadata of a sprite sheet of uniform cells,
+// such as a sheet from another tool, with every frame lasting delay centisec.
+//
+// Zero frames indicates every cell. Partial grids leave trailing cells unused.
+func NewSpriteGrid(width, height, columns, rows, frames, delay int) (SpriteSheet, error) {
+	if columns < 1 || rows < 1 {
+		return SpriteSheet{}, fmt.Errorf("%w: grid %dx%d", ErrInvalidSpriteSheet, columns, rows)
+	}
+
+	if width%columns != 0 || height%rows != 0 {
+		return SpriteSheet{}, fmt.Errorf("%w: %dx%d sheet does not divide into %dx%d cells", ErrInvalidSpriteSheet, width, height, columns, rows)
+	}
+
+	if frames == 0 {
+		frames = columns * rows
+	}
+
+	if frames < 0 || frames > columns*rows {
+		return SpriteSheet{}, fmt.Errorf("%w: %d frames in a %dx%d grid", ErrInvalidSpriteSheet, frames, columns, rows)
+	}
+
+	cellWidth, cellHeight := width/columns, height/rows
+	sheet := SpriteSheet{Width: width, Height: height, Frames: make([]SpriteFrame, frames)}
+
+	for i := range sheet.Frames {
+		sheet.Frames[i] = SpriteFrame{X: i % columns * cellWidth, Y: i / columns * cellHeight, Width: cellWidth, Height: cellHeight, Delay: delay}
+	}
+
+	return sheet, nil
+}
+
+// Slice cuts the frames of a sheet image into an animation.
+func (o SpriteSheet) Slice(sheetImage image.Image) (*Animation, error) {
+	if len(o.Frames) == 0 {
+		return nil, ErrNoFrames
+	}
+
+	bounds := sheetImage.Bounds()
+
+	if (o.Width != 0 && o.Width != bounds.Dx()) || (o.Height != 0 && o.Height != bounds.Dy()) {
+		return nil, fmt.Errorf("%w: expected a %dx%d sheet, got %dx%d", ErrInvalidSpriteSheet, o.Width, o.Height, bounds.Dx(), bounds.Dy())
+	}
+
+	nrgba := toNRGBA(sheetImage, bounds)
+	timeline := make(Timeline, len(o.Frames))
+
+	for i, frame := range o.Frames {
+		area := frame.Rect().Add(bounds.Min)
+
+		if area.Empty() || !area.In(bounds) {
+			return nil, fmt.Errorf("%w: frame %d area %v exceeds the sheet", ErrInvalidSpriteSheet, i, frame.Rect())
+		}
+
+		timeline[i] = Frame{Image: cropNRGBA(nrgba, area), Delay: frame.Delay}
+	}
+
+	return &Animation{Timeline: timeline, LoopCount: o.LoopCount}, nil
+}
+
+// ExportSpriteSheet writes the frames of an animation as a grid PNG,
+// along with <sheet>.json metadata, see NewSpriteSheet.
+func ExportSpriteSheet(pth string, animation *Animation, columns int) (SpriteSheet, error) {
+	sheetImage, sheet, err := NewSpriteSheet(animation, columns)
+	if err != nil {
+		return SpriteSheet{}, err
+	}
+
+	sheet.Image = filepath.Base(pth)
+
+	if err2 := writePNG(pth, sheetImage); err2 != nil {
+		return SpriteSheet{}, err2
+	}
+
+	sheetJSON, err := json.MarshalIndent(sheet, "", "  ")
+	if err != nil {
+		return SpriteSheet{}, err
+	}
+
+	return sheet, writeText(SpriteSidecar(pth, ".json"), string(sheetJSON)+"\n")
+}
+
+// ImportSpriteSheet slices a sheet image into an animation,
+// per its <sheet>.json metadata, see ExportSpriteSheet.
+func ImportSpriteSheet(pth string) (*Animation, error) {
+	metadataPth := SpriteSidecar(pth, ".json")
+	sheetJSON, err := os.ReadFile(metadataPth)
+	if err != nil {
+		return nil, err
+	}
+
+	var sheet SpriteSheet
+
+	if err2 := json.Unmarshal(sheetJSON, &sheet); err2 != nil {
+		return nil, fmt.Errorf("%v: %w", metadataPth, err2)
+	}
+
+	sheetImage, err := readImage(pth)
+	if err != nil {
+		return nil, err
+	}
+
+	return sheet.Slice(sheetImage)
+}
+
+// ExportSpriteCSS writes a <sheet>.css stylesheet animating the sheet with steps(),
+// along with a <sheet>.html preview page.
+//
+// Frames play at their own delays, with 0cs and 1cs delays lasting 10cs, as browsers play GIFs.
+// The stylesheet assumes uniform cells, as laid out by NewSpriteSheet.
+func ExportSpriteCSS(pth string, sheet SpriteSheet) error {
+	if len(sheet.Frames) == 0 {
+		return ErrNoFrames
+	}
+
+	class := cssIdentifier(strings.TrimSuffix(filepath.Base(pth), filepath.Ext(pth)))
+	cssPth := SpriteSidecar(pth, ".css")
+
+	if err := writeText(cssPth, spriteCSS(sheet, class)); err != nil {
+		return err
+	}
+
+	return writeText(SpriteSidecar(pth, ".html"), spriteHTML(filepath.Base(cssPth), class))
+}
+
+// spriteCSS renders a stylesheet stepping through the frames of a sheet,
+// holding each frame for its delay.
+func spriteCSS(sheet SpriteSheet, class string) string {
+	delays := make([]int, len(sheet.Frames))
+	total := 0
+
+	for i, frame := range sheet.Frames {
+		delays[i] = frame.Delay
+
+		if delays[i] < 2 {
+			delays[i] = 10
+		}
+
+		total += delays[i]
+	}
+
+	iterations := "infinite"
+
+	if sheet.LoopCount != 0 {
+		iterations = strconv.Itoa((&Animation{LoopCount: sheet.LoopCount}).Plays())
+	}
+
+	var b strings.Builder
+	first := sheet.Frames[0]
+	fmt.Fprintf(&b, ".%v {\n", class)
+	fmt.Fprintf(&b, "  width: %dpx;\n", first.Width)
+	fmt.Fprintf(&b, "  height: %dpx;\n", first.Height)
+	fmt.Fprintf(&b, "  background: url(\"%v\") no-repeat;\n", url.PathEscape(sheet.Image))
+	fmt.Fprintf(&b, "  animation: %v %dms steps(1, end) %v;\n", class, 10*total, iterations)
+	fmt.Fprintf(&b, "}\n\n")
+	fmt.Fprintf(&b, "@keyframes %v {\n", class)
+	elapsed := 0
+
+	for i, frame := range sheet.Frames {
+		fmt.Fprintf(&b, "  %v { background-position: %dpx %dpx; }\n", cssPercent(elapsed, total), -frame.X, -frame.Y)
+		elapsed += delays[i]
+	}
+
+	last := sheet.Frames[len(sheet.Frames)-1]
+	fmt.Fprintf(&b, "  100%% { background-position: %dpx %dpx; }\n", -last.X, -last.Y)
+	fmt.Fprintf(&b, "}\n")
+	return b.String()
+}
+
+// spriteHTML renders a page previewing a sprite animation.
+func spriteHTML(cssName, class string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n")
+	fmt.Fprintf(&b, "<html>\n<head>\n")
+	fmt.Fprintf(&b, "<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%v</title>\n", html.EscapeString(class))
+	fmt.Fprintf(&b, "<link rel=\"stylesheet\" href=\"%v\">\n", html.EscapeString(url.PathEscape(cssName)))
+	fmt.Fprintf(&b, "</head>\n<body>\n")
+	fmt.Fprintf(&b, "<div class=\"%v\"></div>\n", class)
+	fmt.Fprintf(&b, "</body>\n</html>\n")
+	return b.String()
+}
+
+// cssPercent formats a keyframe offset.
+func cssPercent(elapsed, total int) string {
+	percent := strconv.FormatFloat(100*float64(elapsed)/float64(total), 'f', 4, 64)
+	return strings.TrimSuffix(strings.TrimRight(percent, "0"), ".") + "%"
+}
+
+// cssIdentifier sanitizes a file name into a CSS class and animation name.
+func cssIdentifier(name string) string {
+	identifier := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '-'
+		}
+	}, name)
+
+	// Identifiers start with a letter or underscore.
+	if first := strings.IndexFunc(identifier, func(r rune) bool { return r != '_' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') }); first == 0 || identifier == "" {
+		identifier = "sprite-" + identifier
+	}
+
+	return identifier
+}
+
+// writeText writes a text file atomically.
+func writeText(pth, text string) error {
+	return WriteFileAtomic(pth, func(w io.Writer) error {
+		_, err := io.WriteString(w, text)
+		return err
+	})
+}
@@ -0,0 +1,102 @@
+package buttery_test
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+func TestSpriteSheetRoundTrip(t *testing.T) {
+	var timeline buttery.Timeline
+
+	for i := 0; i < 5; i++ {
+		img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+		img.SetNRGBA(i%3, i%2, color.NRGBA{R: uint8(50 * i), G: 0x80, A: uint8(0xFF - 40*i)})
+		timeline = append(timeline, buttery.Frame{Image: img, Delay: i + 2})
+	}
+
+	sourceAnimation := &buttery.Animation{Timeline: timeline, LoopCount: 2}
+	pth := filepath.Join(t.TempDir(), "walk.png")
+	sheet, err := buttery.ExportSpriteSheet(pth, sourceAnimation, 0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sheet.Width != 9 || sheet.Height != 4 {
+		t.Errorf("expected a 9x4 sheet, got %dx%d", sheet.Width, sheet.Height)
+	}
+
+	if err := buttery.ExportSpriteCSS(pth, sheet); err != nil {
+		t.Fatal(err)
+	}
+
+	css, err := os.ReadFile(buttery.SpriteSidecar(pth, ".css"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(css), "steps(1, end) 3;") || !strings.Contains(string(css), "background-position: -3px -2px;") {
+		t.Errorf("expected a three play steps() animation through each cell, got %s", css)
+	}
+
+	animation, err := buttery.ImportSpriteSheet(pth)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if animation.LoopCount != sourceAnimation.LoopCount || !slices.Equal(animation.Timeline.Delays(), sourceAnimation.Timeline.Delays()) {
+		t.Errorf("expected delays %v and loop count %d, got %v and %d", sourceAnimation.Timeline.Delays(), sourceAnimation.LoopCount, animation.Timeline.Delays(), animation.LoopCount)
+	}
+
+	if len(animation.Timeline) != len(timeline) {
+		t.Fatalf("expected %d frames, got %d", len(timeline), len(animation.Timeline))
+	}
+
+	for i, frame := range timeline {
+		expected, actual := frame.Image.(*image.NRGBA), animation.Timeline[i].Image.(*image.NRGBA)
+
+		if actual.Rect != expected.Rect || !slices.Equal(actual.Pix, expected.Pix) {
+			t.Errorf("expected frame %d to round trip", i)
+		}
+	}
+}
+
+func TestNewSpriteGrid(t *testing.T) {
+	sheet, err := buttery.NewSpriteGrid(8, 6, 4, 2, 7, 5)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sheet.Frames) != 7 || sheet.Frames[6].Rect() != image.Rect(4, 3, 6, 6) || sheet.Frames[6].Delay != 5 {
+		t.Errorf("expected 7 frames of 2x3 cells, got %v", sheet.Frames)
+	}
+
+	animation, err := sheet.Slice(image.NewGray(image.Rect(0, 0, 8, 6)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(animation.Timeline) != 7 || animation.Timeline[0].Image.Bounds() != image.Rect(0, 0, 2, 3) {
+		t.Errorf("expected 7 frames of 2x3 images, got %d", len(animation.Timeline))
+	}
+
+	if _, err := buttery.NewSpriteGrid(8, 6, 3, 2, 0, 5); !errors.Is(err, buttery.ErrInvalidSpriteSheet) {
+		t.Errorf("expected ErrInvalidSpriteSheet for uneven cells, got %v", err)
+	}
+
+	if _, err := sheet.Slice(image.NewGray(image.Rect(0, 0, 4, 6))); !errors.Is(err, buttery.ErrInvalidSpriteSheet) {
+		t.Errorf("expected ErrInvalidSpriteSheet for a mismatched sheet, got %v", err)
+	}
+}
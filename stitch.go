@@ -1,63 +1,470 @@
-//go:generate stringer -type=Stitch
-
 // Package buttery provides primitives for manipulating GIF animations.
 package buttery
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"image/color"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 )
 
-// Stitch models a loop continuity strategy.
-type Stitch int
+// Stitcher models a loop continuity strategy.
+type Stitcher interface {
+	// Stitch applies the transition to a Timeline.
+	//
+	// Long running Stitchers should honor cancellation,
+	// and may report progress with ReportProgress.
+	Stitch(ctx context.Context, timeline Timeline) (Timeline, error)
+}
 
-const (
-	// None ends the incoming sequence as-is.
-	None Stitch = iota
+// StitchParam declares a stitch specific parameter.
+type StitchParam struct {
+	// Name denotes the parameter key.
+	Name string
 
-	// Mirror follows the end of the incoming sequence by replaying the sequence backwards.
-	Mirror
+	// Default denotes the value assumed when the parameter is absent.
+	Default string
+
+	// Usage briefly describes the parameter.
+	Usage string
+}
 
-	// FlipH follows the end of the incoming sequence by replaying the sequence reflected horizontally.
-	FlipH
+// StitchParams models stitch specific parameter values, keyed by name.
+type StitchParams map[string]string
 
-	// FlipV follows the end of the incoming sequence by replaying the sequence reflected vertically.
-	FlipV
+// Float parses a floating point parameter.
+func (o StitchParams) Float(name string) (float64, error) {
+	f, err := strconv.ParseFloat(o[name], 64)
 
-	// Shuffle randomizes the incoming sequence.
-	Shuffle
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0.0, fmt.Errorf("%w: %v: %v", ErrInvalidStitchParam, name, o[name])
+	}
 
-	// PanH shifts the canvas horizontally
-	PanH
+	return f, nil
+}
 
-	// PanV shifts the canvas vertically
-	PanV
+// Color parses a 0xRRGGBB parameter.
+func (o StitchParams) Color(name string) (color.RGBA, error) {
+	c, err := ParseColor(o[name])
 
-	// Fade applies time color gradients
-	Fade
-)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("%w: %v: %v", ErrInvalidStitchParam, name, o[name])
+	}
 
-// ParseStitch generates a Stitch from a string value.
-func ParseStitch(s string) (*Stitch, bool) {
+	return c, nil
+}
+
+// StitchFactory generates Stitchers.
+type StitchFactory interface {
+	// Params declares the supported parameters, in positional order.
+	Params() []StitchParam
+
+	// New generates a Stitcher, validating the parameters.
 	//
-	// /!\ Manually update upper bound for each new enum value /!\
+	// Absent parameters receive their declared defaults.
+	New(params StitchParams) (Stitcher, error)
+}
+
+// stitchFactory adapts a function into a StitchFactory.
+type stitchFactory struct {
+	params []StitchParam
+	new    func(params StitchParams) (Stitcher, error)
+}
+
+// Params declares the supported parameters, in positional order.
+func (o stitchFactory) Params() []StitchParam { return o.params }
+
+// New generates a Stitcher, validating the parameters.
+func (o stitchFactory) New(params StitchParams) (Stitcher, error) { return o.new(params) }
+
+// stitchRegistration pairs a StitchFactory with its canonical name.
+type stitchRegistration struct {
+	name    string
+	factory StitchFactory
+}
+
+// stitchRegistry indexes stitch registrations by lowercase name.
+var stitchRegistry = map[string]stitchRegistration{}
+
+// stitchRegistryMutex guards stitchRegistry.
+var stitchRegistryMutex sync.RWMutex
+
+// RegisterStitch makes a stitch available by name.
+//
+// Names are case insensitive, and must not contain spaces or separators (:,|=).
+//
+// RegisterStitch panics on empty, malformed, or duplicate names.
+func RegisterStitch(name string, factory StitchFactory) {
+	if name == "" || strings.ContainsAny(name, ":,|= ") {
+		panic(fmt.Sprintf("buttery: invalid stitch name: %q", name))
+	}
+
+	if factory == nil {
+		panic(fmt.Sprintf("buttery: nil stitch factory: %v", name))
+	}
+
+	key := strings.ToLower(name)
+	stitchRegistryMutex.Lock()
+	defer stitchRegistryMutex.Unlock()
+
+	if _, ok := stitchRegistry[key]; ok {
+		panic(fmt.Sprintf("buttery: duplicate stitch: %v", name))
+	}
+
+	stitchRegistry[key] = stitchRegistration{name: name, factory: factory}
+}
+
+// lookupStitch queries the registry by case insensitive name.
+func lookupStitch(name string) (stitchRegistration, bool) {
+	stitchRegistryMutex.RLock()
+	defer stitchRegistryMutex.RUnlock()
+	registration, ok := stitchRegistry[strings.ToLower(name)]
+	return registration, ok
+}
+
+// Stitches lists the registered stitch names, sorted.
+func Stitches() []string {
+	stitchRegistryMutex.RLock()
+	defer stitchRegistryMutex.RUnlock()
+	var names []string
+
+	for _, registration := range stitchRegistry {
+		names = append(names, registration.name)
+	}
+
+	slices.Sort(names)
+	return names
+}
+
+// StitchParamsFor queries the parameters declared by a registered stitch.
+func StitchParamsFor(name string) ([]StitchParam, bool) {
+	registration, ok := lookupStitch(name)
+
+	if !ok {
+		return nil, false
+	}
+
+	return registration.factory.Params(), true
+}
+
+// Stitch references a registered stitch, with parameters.
+//
+// The text form is Name[:param[,param...]],
+// where each param is either a positional value or a key=value pair.
+//
+// Example: "Fade:0xffffff,rate=0.5"
+type Stitch struct {
+	// Name denotes a registered stitch.
+	Name string
+
+	// Params denotes stitch specific parameters (Default empty).
 	//
-	for i := None; i <= Fade; i++ {
-		if s == i.String() {
-			return &i, true
+	// Absent parameters receive their declared defaults.
+	Params StitchParams
+}
+
+// None ends the incoming sequence as-is.
+var None = Stitch{Name: "None"}
+
+// Mirror follows the end of the incoming sequence by replaying the sequence backwards.
+var Mirror = Stitch{Name: "Mirror"}
+
+// FlipH follows the end of the incoming sequence by replaying the sequence reflected horizontally.
+var FlipH = Stitch{Name: "FlipH"}
+
+// FlipV follows the end of the incoming sequence by replaying the sequence reflected vertically.
+var FlipV = Stitch{Name: "FlipV"}
+
+// Shuffle randomizes the incoming sequence.
+var Shuffle = Stitch{Name: "Shuffle"}
+
+// PanH shifts the canvas horizontally, by velocity pixels per frame.
+var PanH = Stitch{Name: "PanH"}
+
+// PanV shifts the canvas vertically, by velocity pixels per frame.
+var PanV = Stitch{Name: "PanV"}
+
+// Fade applies time color gradients, towards color at rate.
+var Fade = Stitch{Name: "Fade"}
+
+func init() {
+	stateless := func(stitcher Stitcher) StitchFactory {
+		return stitchFactory{new: func(StitchParams) (Stitcher, error) { return stitcher, nil }}
+	}
+
+	RegisterStitch(None.Name, stateless(noneStitcher{}))
+	RegisterStitch(Mirror.Name, stateless(mirrorStitcher{}))
+	RegisterStitch(FlipH.Name, stateless(flipStitcher{horizontal: true}))
+	RegisterStitch(FlipV.Name, stateless(flipStitcher{}))
+	RegisterStitch(Shuffle.Name, stateless(shuffleStitcher{}))
+
+	panParams := []StitchParam{{Name: "velocity", Default: "1", Usage: "how many pixels to pan per frame"}}
+
+	RegisterStitch(PanH.Name, stitchFactory{
+		params: panParams,
+		new: func(params StitchParams) (Stitcher, error) {
+			velocity, err := params.Float("velocity")
+			return panStitcher{dx: velocity}, err
+		},
+	})
+	RegisterStitch(PanV.Name, stitchFactory{
+		params: panParams,
+		new: func(params StitchParams) (Stitcher, error) {
+			velocity, err := params.Float("velocity")
+			return panStitcher{dy: velocity}, err
+		},
+	})
+	RegisterStitch(Fade.Name, stitchFactory{
+		params: []StitchParam{
+			{Name: "color", Default: "0x000000", Usage: "fade color (0xRRGGBB)"},
+			{Name: "rate", Default: "1", Usage: "fade velocity factor"},
+		},
+		new: func(params StitchParams) (Stitcher, error) {
+			c, err := params.Color("color")
+
+			if err != nil {
+				return nil, err
+			}
+
+			rate, err := params.Float("rate")
+			return fadeStitcher{color: c, rate: rate}, err
+		},
+	})
+}
+
+// noneStitcher implements None.
+type noneStitcher struct{}
+
+// Stitch applies the transition to a Timeline.
+func (o noneStitcher) Stitch(ctx context.Context, timeline Timeline) (Timeline, error) {
+	return timeline, nil
+}
+
+// mirrorStitcher implements Mirror.
+type mirrorStitcher struct{}
+
+// Stitch applies the transition to a Timeline.
+func (o mirrorStitcher) Stitch(ctx context.Context, timeline Timeline) (Timeline, error) {
+	return timeline.Mirror(), nil
+}
+
+// flipStitcher implements FlipH / FlipV.
+type flipStitcher struct {
+	horizontal bool
+}
+
+// Stitch applies the transition to a Timeline.
+func (o flipStitcher) Stitch(ctx context.Context, timeline Timeline) (Timeline, error) {
+	if o.horizontal {
+		return timeline.FlipHContext(ctx)
+	}
+
+	return timeline.FlipVContext(ctx)
+}
+
+// shuffleStitcher implements Shuffle.
+type shuffleStitcher struct{}
+
+// Stitch applies the transition to a Timeline.
+func (o shuffleStitcher) Stitch(ctx context.Context, timeline Timeline) (Timeline, error) {
+	return timeline.Shuffle(), nil
+}
+
+// panStitcher implements PanH / PanV.
+type panStitcher struct {
+	dx, dy float64
+}
+
+// Stitch applies the transition to a Timeline.
+func (o panStitcher) Stitch(ctx context.Context, timeline Timeline) (Timeline, error) {
+	return timeline.PanContext(ctx, o.dx, o.dy)
+}
+
+// fadeStitcher implements Fade.
+type fadeStitcher struct {
+	color color.RGBA
+	rate  float64
+}
+
+// Stitch applies the transition to a Timeline.
+func (o fadeStitcher) Stitch(ctx context.Context, timeline Timeline) (Timeline, error) {
+	return timeline.FadeContext(ctx, o.color, o.rate)
+}
+
+// ParseColor generates a color from a 0xRRGGBB string value.
+func ParseColor(s string) (color.RGBA, error) {
+	u, err := strconv.ParseUint(s, 0, 32)
+
+	if err != nil {
+		return color.RGBA{}, err
+	}
+
+	if u > 0xFFFFFF {
+		return color.RGBA{}, fmt.Errorf("color out of range: %v", s)
+	}
+
+	return color.RGBA{
+		R: uint8((u >> 16) & 0xFF),
+		G: uint8((u >> 8) & 0xFF),
+		B: uint8(u & 0xFF),
+		A: 0x00,
+	}, nil
+}
+
+// ParseStitch generates a Stitch from a case insensitive string value.
+func ParseStitch(s string) (*Stitch, bool) {
+	var stitch Stitch
+
+	if err := stitch.UnmarshalText([]byte(s)); err != nil {
+		return nil, false
+	}
+
+	return &stitch, true
+}
+
+// Is reports whether the Stitch references the given name, case insensitively.
+func (o Stitch) Is(name string) bool { return strings.EqualFold(o.Name, name) }
+
+// With generates a copy of the Stitch with an additional parameter.
+func (o Stitch) With(name, value string) Stitch {
+	params := make(StitchParams, len(o.Params)+1)
+
+	for k, v := range o.Params {
+		params[k] = v
+	}
+
+	params[name] = value
+	o.Params = params
+	return o
+}
+
+// Stitcher resolves the Stitch from the registry.
+func (o Stitch) Stitcher() (Stitcher, error) {
+	registration, ok := lookupStitch(o.Name)
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownStitch, o.Name)
+	}
+
+	declared := registration.factory.Params()
+	params := make(StitchParams, len(declared))
+
+	for _, param := range declared {
+		params[param.Name] = param.Default
+	}
+
+	for k, v := range o.Params {
+		if _, ok := params[k]; !ok {
+			return nil, fmt.Errorf("stitch %v: %w: unknown %v", registration.name, ErrInvalidStitchParam, k)
 		}
+
+		params[k] = v
 	}
 
-	return nil, false
+	stitcher, err := registration.factory.New(params)
+
+	if err != nil {
+		return nil, fmt.Errorf("stitch %v: %v", registration.name, err)
+	}
+
+	return stitcher, nil
 }
 
-// Validate rejects out of bound values.
+// Validate rejects unknown stitches and invalid parameters.
 func (o Stitch) Validate() error {
-	//
-	// /!\ Manually update upper bound for each new enum value /!\
-	//
-	if o < None || o > Fade {
-		return fmt.Errorf("invalid stitch value: %d", o)
+	_, err := o.Stitcher()
+	return err
+}
+
+// String renders the Stitch in text form.
+func (o Stitch) String() string {
+	text, err := o.MarshalText()
+
+	if err != nil {
+		return o.Name
+	}
+
+	return string(text)
+}
+
+// MarshalText renders the Stitch in text form,
+// with parameters as key=value pairs in declared order.
+func (o Stitch) MarshalText() ([]byte, error) {
+	if o.Name == "" {
+		return nil, errors.New("stitch name cannot be blank")
+	}
+
+	declared, _ := StitchParamsFor(o.Name)
+	var keys []string
+	var undeclared []string
+
+	for _, param := range declared {
+		if _, ok := o.Params[param.Name]; ok {
+			keys = append(keys, param.Name)
+		}
+	}
+
+	for k := range o.Params {
+		if !slices.Contains(keys, k) {
+			undeclared = append(undeclared, k)
+		}
+	}
+
+	slices.Sort(undeclared)
+	keys = append(keys, undeclared...)
+
+	if len(keys) == 0 {
+		return []byte(o.Name), nil
+	}
+
+	var pairs []string
+
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, o.Params[k]))
+	}
+
+	return []byte(fmt.Sprintf("%s:%s", o.Name, strings.Join(pairs, ","))), nil
+}
+
+// UnmarshalText parses a Stitch from text form.
+//
+// Names are case insensitive, and canonicalized to their registered form.
+// Positional parameters follow the stitch's declared parameter order.
+func (o *Stitch) UnmarshalText(text []byte) error {
+	name, argsString, hasArgs := strings.Cut(strings.TrimSpace(string(text)), ":")
+	registration, ok := lookupStitch(name)
+
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrUnknownStitch, name)
+	}
+
+	declared := registration.factory.Params()
+	var params StitchParams
+
+	if hasArgs {
+		params = StitchParams{}
+
+		for i, arg := range strings.Split(argsString, ",") {
+			arg = strings.TrimSpace(arg)
+			k, v, named := strings.Cut(arg, "=")
+
+			if !named {
+				if i >= len(declared) {
+					return fmt.Errorf("stitch %v: %w: too many", registration.name, ErrInvalidStitchParam)
+				}
+
+				k, v = declared[i].Name, arg
+			}
+
+			params[k] = v
+		}
 	}
 
-	return nil
+	*o = Stitch{Name: registration.name, Params: params}
+	return o.Validate()
 }
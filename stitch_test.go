@@ -1,6 +1,8 @@
 package buttery_test
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/mcandre/buttery"
@@ -20,3 +22,54 @@ func TestStitchMarshaling(t *testing.T) {
 		t.Errorf("expected symmetric marshaling for stitch %v", stitch)
 	}
 }
+
+type doubleStitcher struct{}
+
+func (o doubleStitcher) Stitch(_ context.Context, timeline buttery.Timeline) (buttery.Timeline, error) {
+	return append(timeline, timeline...), nil
+}
+
+type doubleFactory struct{}
+
+func (o doubleFactory) Params() []buttery.StitchParam {
+	return []buttery.StitchParam{{Name: "times", Default: "2"}}
+}
+
+func (o doubleFactory) New(params buttery.StitchParams) (buttery.Stitcher, error) {
+	if params["times"] != "2" {
+		return nil, errors.New("times must be 2")
+	}
+
+	return doubleStitcher{}, nil
+}
+
+// The registry is global, so register once per test binary, even with -count.
+func init() {
+	buttery.RegisterStitch("Double", doubleFactory{})
+}
+
+func TestStitchRegistry(t *testing.T) {
+	var stitch buttery.Stitch
+
+	if err := stitch.UnmarshalText([]byte("double:2")); err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := stitch.MarshalText()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(text) != "Double:times=2" {
+		t.Errorf("expected canonical stitch text Double:times=2, got %v", string(text))
+	}
+
+	if err := stitch.UnmarshalText([]byte("Double:times=3")); err == nil {
+		t.Errorf("expected invalid parameter error")
+	}
+
+	if err := stitch.UnmarshalText([]byte("Fade:bogus=1")); err == nil {
+		t.Errorf("expected unknown parameter error")
+	}
+}
@@ -0,0 +1,503 @@
+package buttery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"math"
+	"math/rand"
+	"slices"
+
+	"github.com/anthonynsimon/bild/transform"
+)
+
+// Frame models a single animation frame.
+type Frame struct {
+	// Image denotes the full canvas for this frame.
+	Image image.Image
+
+	// Delay denotes the frame duration in centisec.
+	Delay int
+
+	// Disposal denotes a GIF disposal method.
+	Disposal byte
+}
+
+// Timeline models a sequence of animation frames.
+//
+// Timeline methods return new Timelines,
+// leaving the receiver and its images unmodified.
+type Timeline []Frame
+
+// NewTimeline generates a Timeline from a GIF,
+// flattening each frame onto a full, opaque canvas.
+func NewTimeline(sourceGif *gif.GIF) (Timeline, error) {
+	if err := validateSource(sourceGif); err != nil {
+		return nil, err
+	}
+
+	return composite(context.Background(), sourceGif, false, false)
+}
+
+// NewTimelineFromImages generates a Timeline from plain images and their delays in centisec.
+func NewTimelineFromImages(images []image.Image, delays []int) (Timeline, error) {
+	if len(images) == 0 {
+		return nil, ErrNoFrames
+	}
+
+	if len(delays) != len(images) {
+		return nil, ErrDelayCount
+	}
+
+	for i, img := range images {
+		if img == nil {
+			return nil, fmt.Errorf("frame %d: %w", i, ErrInvalidFrame)
+		}
+	}
+
+	timeline := make(Timeline, len(images))
+
+	for i, img := range images {
+		timeline[i] = Frame{Image: img, Delay: delays[i], Disposal: gif.DisposalNone}
+	}
+
+	return timeline, nil
+}
+
+// Images queries the frame images.
+func (o Timeline) Images() []image.Image {
+	images := make([]image.Image, len(o))
+
+	for i, f := range o {
+		images[i] = f.Image
+	}
+
+	return images
+}
+
+// Delays queries the frame delays in centisec.
+func (o Timeline) Delays() []int {
+	delays := make([]int, len(o))
+
+	for i, f := range o {
+		delays[i] = f.Delay
+	}
+
+	return delays
+}
+
+// Reverse plays the sequence backwards.
+func (o Timeline) Reverse() Timeline {
+	reversed := slices.Clone(o)
+	slices.Reverse(reversed)
+	return reversed
+}
+
+// Trim removes frames from the start and end of the sequence.
+func (o Timeline) Trim(start, end int) (Timeline, error) {
+	if start < 0 || end < 0 {
+		return nil, fmt.Errorf("trims %w", ErrNegative)
+	}
+
+	if start >= len(o) || end >= len(o)-start {
+		return nil, ErrTooFewFrames
+	}
+
+	return slices.Clone(o[start : len(o)-end]), nil
+}
+
+// Window truncates the sequence to a fixed frame count.
+func (o Timeline) Window(length int) (Timeline, error) {
+	if length < 1 {
+		return nil, ErrWindowTooShort
+	}
+
+	if length > len(o) {
+		return nil, ErrWindowTooLong
+	}
+
+	return slices.Clone(o[:length]), nil
+}
+
+// Cut removes every nth frame from the sequence.
+func (o Timeline) Cut(interval int) (Timeline, error) {
+	if interval < 2 {
+		return nil, ErrCutIntervalTooShort
+	}
+
+	var reduced Timeline
+
+	for i, f := range o {
+		if (1+i)%interval != 0 {
+			reduced = append(reduced, f)
+		}
+	}
+
+	return reduced, nil
+}
+
+// Shift rotates the sequence leftward.
+//
+// A negative offset rotates the sequence rightward.
+func (o Timeline) Shift(offset int) Timeline {
+	shifted := make(Timeline, len(o))
+
+	if len(o) == 0 {
+		return shifted
+	}
+
+	offset = signedMod(offset, len(o))
+
+	for i := range o {
+		shifted[i] = o[(i+offset)%len(o)]
+	}
+
+	return shifted
+}
+
+// ScaleDelay multiplies each frame delay by a factor.
+//
+// The resulting delay is upheld to a lower bound of 2 centisec.
+func (o Timeline) ScaleDelay(factor float64) Timeline {
+	scaled := slices.Clone(o)
+
+	for i, f := range scaled {
+		scaled[i].Delay = int(math.Max(2.0, factor*float64(f.Delay)))
+	}
+
+	return scaled
+}
+
+// Mirror follows the sequence by replaying it backwards.
+func (o Timeline) Mirror() Timeline {
+	mirrored := slices.Clone(o)
+
+	for i := len(o) - 2; i >= 0; i-- {
+		mirrored = append(mirrored, o[i])
+	}
+
+	return mirrored
+}
+
+// FlipH follows the sequence by replaying it reflected horizontally.
+func (o Timeline) FlipH() Timeline {
+	return append(slices.Clone(o), o.mapImages(func(_ int, img image.Image) image.Image {
+		return flipImage(img, true)
+	})...)
+}
+
+// FlipHContext follows the sequence by replaying it reflected horizontally,
+// reporting progress and honoring cancellation.
+func (o Timeline) FlipHContext(ctx context.Context) (Timeline, error) {
+	flipped, err := o.mapImagesContext(ctx, StageStitch, func(_ int, img image.Image) image.Image {
+		return flipImage(img, true)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(slices.Clone(o), flipped...), nil
+}
+
+// FlipV follows the sequence by replaying it reflected vertically.
+func (o Timeline) FlipV() Timeline {
+	return append(slices.Clone(o), o.mapImages(func(_ int, img image.Image) image.Image {
+		return flipImage(img, false)
+	})...)
+}
+
+// FlipVContext follows the sequence by replaying it reflected vertically,
+// reporting progress and honoring cancellation.
+func (o Timeline) FlipVContext(ctx context.Context) (Timeline, error) {
+	flipped, err := o.mapImagesContext(ctx, StageStitch, func(_ int, img image.Image) image.Image {
+		return flipImage(img, false)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(slices.Clone(o), flipped...), nil
+}
+
+// Shuffle randomizes the sequence.
+func (o Timeline) Shuffle() Timeline {
+	shuffled := slices.Clone(o)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled
+}
+
+// Pan offsets each successive frame's canvas by a further dx, dy pixels, wrapping around the edges.
+func (o Timeline) Pan(dx, dy float64) Timeline {
+	return o.mapImages(func(i int, img image.Image) image.Image {
+		return panImage(img, int(float64(i)*dx), int(float64(i)*dy))
+	})
+}
+
+// PanContext offsets each successive frame's canvas by a further dx, dy pixels, wrapping around the edges,
+// reporting progress and honoring cancellation.
+func (o Timeline) PanContext(ctx context.Context, dx, dy float64) (Timeline, error) {
+	return o.mapImagesContext(ctx, StageStitch, func(i int, img image.Image) image.Image {
+		return panImage(img, int(float64(i)*dx), int(float64(i)*dy))
+	})
+}
+
+// Fade applies a time color gradient towards a target hue,
+// strongest at the ends of the sequence and weakest in the middle.
+//
+// Alpha channel of target ignored.
+func (o Timeline) Fade(target color.RGBA, rate float64) Timeline {
+	amounts := fadeAmounts(len(o), rate)
+
+	return o.mapImages(func(i int, img image.Image) image.Image {
+		return fadeImage(img, target, amounts[i])
+	})
+}
+
+// FadeContext applies a time color gradient towards a target hue,
+// strongest at the ends of the sequence and weakest in the middle,
+// reporting progress and honoring cancellation.
+//
+// Alpha channel of target ignored.
+func (o Timeline) FadeContext(ctx context.Context, target color.RGBA, rate float64) (Timeline, error) {
+	amounts := fadeAmounts(len(o), rate)
+
+	return o.mapImagesContext(ctx, StageFade, func(i int, img image.Image) image.Image {
+		return fadeImage(img, target, amounts[i])
+	})
+}
+
+// fadeAmounts computes the blend amount for each frame of a Fade,
+// in the interval [0, 1].
+func fadeAmounts(timelineLen int, rate float64) []float64 {
+	amounts := make([]float64, timelineLen)
+
+	if timelineLen < 2 {
+		return amounts
+	}
+
+	s := float64(timelineLen) - 1.0
+
+	for i := range amounts {
+		amounts[i] = s / float64(timelineLen-1)
+
+		if i < timelineLen/2 {
+			s -= rate
+		} else if i > timelineLen/2 {
+			s += rate
+		}
+
+		s = min(max(s, 0.0), float64(timelineLen)-1.0)
+	}
+
+	return amounts
+}
+
+// mapImages transforms each frame image.
+func (o Timeline) mapImages(f func(i int, img image.Image) image.Image) Timeline {
+	mapped := slices.Clone(o)
+
+	for i, fr := range mapped {
+		mapped[i].Image = f(i, fr.Image)
+	}
+
+	return mapped
+}
+
+// mapImagesContext transforms each frame image concurrently,
+// reporting progress and honoring cancellation.
+//
+// While streaming, images load from and return to the frame cache.
+func (o Timeline) mapImagesContext(ctx context.Context, stage Stage, f func(i int, img image.Image) image.Image) (Timeline, error) {
+	mapped := slices.Clone(o)
+	cache := getFrameCache(ctx)
+
+	if cache == nil {
+		if err := forEachFrame(ctx, stage, len(mapped), func(i int) func() {
+			return func() { mapped[i].Image = f(i, o[i].Image) }
+		}); err != nil {
+			return nil, err
+		}
+
+		return mapped, nil
+	}
+
+	errs := make([]error, len(mapped))
+
+	if err := forEachFrame(ctx, stage, len(mapped), func(i int) func() {
+		return func() {
+			img, err := cache.load(o[i].Image)
+
+			if err == nil {
+				mapped[i].Image, err = cache.store(f(i, img))
+			}
+
+			errs[i] = err
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return mapped, nil
+}
+
+// flipImage reflects an image horizontally or vertically.
+func flipImage(img image.Image, horizontal bool) image.Image {
+	if planned, ok := img.(*planImage); ok {
+		if horizontal {
+			return planned.with("fliph")
+		}
+
+		return planned.with("flipv")
+	}
+
+	paletted, ok := img.(*image.Paletted)
+
+	if !ok {
+		if horizontal {
+			return transform.FlipH(img)
+		}
+
+		return transform.FlipV(img)
+	}
+
+	bounds := paletted.Bounds()
+	flipped := image.NewPaletted(bounds, paletted.Palette)
+
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			dstX, dstY := x, y
+
+			if horizontal {
+				dstX = bounds.Max.X - 1 - (x - bounds.Min.X)
+			} else {
+				dstY = bounds.Max.Y - 1 - (y - bounds.Min.Y)
+			}
+
+			flipped.SetColorIndex(dstX, dstY, paletted.ColorIndexAt(x, y))
+		}
+	}
+
+	return flipped
+}
+
+// panImage offsets an image by the given horizontal and vertical offsets, wrapping around the edges.
+func panImage(img image.Image, dx, dy int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width == 0 || height == 0 {
+		return img
+	}
+
+	dx, dy = signedMod(dx, width), signedMod(dy, height)
+
+	if planned, ok := img.(*planImage); ok {
+		if dx == 0 && dy == 0 {
+			return img
+		}
+
+		return planned.with(fmt.Sprintf("pan(%d,%d)", dx, dy))
+	}
+
+	pannedPoint := func(x, y int) (int, int) {
+		return bounds.Min.X + signedMod(x-bounds.Min.X+dx, width), bounds.Min.Y + signedMod(y-bounds.Min.Y+dy, height)
+	}
+
+	if paletted, ok := img.(*image.Paletted); ok {
+		panned := image.NewPaletted(bounds, paletted.Palette)
+
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+				dstX, dstY := pannedPoint(x, y)
+				panned.SetColorIndex(dstX, dstY, paletted.ColorIndexAt(x, y))
+			}
+		}
+
+		return panned
+	}
+
+	panned := image.NewRGBA(bounds)
+
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			dstX, dstY := pannedPoint(x, y)
+			panned.Set(dstX, dstY, img.At(x, y))
+		}
+	}
+
+	return panned
+}
+
+// fadeImage blends an image's colors towards a target hue by the given amount.
+//
+// Paletted images retain their pixels and receive a blended palette.
+func fadeImage(img image.Image, target color.RGBA, amount float64) image.Image {
+	if planned, ok := img.(*planImage); ok {
+		if amount == 0 {
+			return img
+		}
+
+		return planned.with(fmt.Sprintf("fade(%.2f)", amount))
+	}
+
+	targetR, targetG, targetB := float64(target.R), float64(target.G), float64(target.B)
+
+	blend := func(c color.Color) color.Color {
+		r, g, b, a := c.RGBA()
+		// Premultiplied channels cannot exceed alpha.
+		aF := float64(a >> 8)
+		rF, gF, bF := float64(r>>8), float64(g>>8), float64(b>>8)
+		rF = min(max(rF+(targetR-rF)*amount, 0.0), aF)
+		gF = min(max(gF+(targetG-gF)*amount, 0.0), aF)
+		bF = min(max(bF+(targetB-bF)*amount, 0.0), aF)
+		return color.RGBA{R: uint8(rF), G: uint8(gF), B: uint8(bF), A: uint8(a >> 8)}
+	}
+
+	if paletted, ok := img.(*image.Paletted); ok {
+		fadedPalette := make(color.Palette, len(paletted.Palette))
+
+		for i, c := range paletted.Palette {
+			fadedPalette[i] = blend(c)
+		}
+
+		fadedPaletted := *paletted
+		fadedPaletted.Palette = fadedPalette
+		return &fadedPaletted
+	}
+
+	bounds := img.Bounds()
+	faded := image.NewRGBA(bounds)
+
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			faded.Set(x, y, blend(img.At(x, y)))
+		}
+	}
+
+	return faded
+}
+
+// signedMod reverses direction for negative n denominators.
+//
+// Warning: Each programming language may implements subtly distinct modulo algorithms.
+// https://en.wikipedia.org/wiki/Modulo
+func signedMod(a, n int) int {
+	m := a % n
+
+	if m != 0 && (m < 0) != (n < 0) {
+		m += n
+	}
+
+	return m
+}
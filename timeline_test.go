@@ -0,0 +1,63 @@
+package buttery_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+func TestTimelineFromImages(t *testing.T) {
+	red := color.RGBA{R: 0xFF, A: 0xFF}
+	img := image.NewRGBA(image.Rect(0, 0, 3, 1))
+	img.Set(0, 0, red)
+	timeline, err := buttery.NewTimelineFromImages([]image.Image{img, img}, []int{5, 7})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flipped := timeline.FlipH()
+
+	if len(flipped) != 4 {
+		t.Fatalf("expected 4 frames, got %d", len(flipped))
+	}
+
+	if c := color.RGBAModel.Convert(flipped[3].Image.At(2, 0)); c != red {
+		t.Errorf("expected reflected pixel %v, got %v", red, c)
+	}
+
+	if flipped[3].Delay != 7 {
+		t.Errorf("expected delay 7, got %d", flipped[3].Delay)
+	}
+
+	panned := timeline.Pan(1.0, 0.0)
+
+	if c := color.RGBAModel.Convert(panned[1].Image.At(1, 0)); c != red {
+		t.Errorf("expected panned pixel %v, got %v", red, c)
+	}
+
+	if c := color.RGBAModel.Convert(img.At(0, 0)); c != red {
+		t.Errorf("expected source image unmodified")
+	}
+}
+
+func TestTimelineEmpty(t *testing.T) {
+	var timeline buttery.Timeline
+
+	for name, edited := range map[string]buttery.Timeline{
+		"Reverse":    timeline.Reverse(),
+		"Shift":      timeline.Shift(3),
+		"ScaleDelay": timeline.ScaleDelay(2),
+		"Mirror":     timeline.Mirror(),
+		"FlipH":      timeline.FlipH(),
+		"Shuffle":    timeline.Shuffle(),
+		"Pan":        timeline.Pan(1, 0),
+		"Fade":       timeline.Fade(color.RGBA{}, 1),
+	} {
+		if len(edited) != 0 {
+			t.Errorf("expected %v to keep an empty timeline empty, got %d frames", name, len(edited))
+		}
+	}
+}
@@ -0,0 +1,798 @@
+package buttery
+
+import (
+	"fmt"
+	"image"
+)
+
+// vp8lSignature opens every VP8L bitstream.
+const vp8lSignature = 0x2F
+
+// VP8L transform types.
+const (
+	vp8lPredictorTransform     = 0
+	vp8lColorTransform         = 1
+	vp8lSubtractGreenTransform = 2
+	vp8lColorIndexingTransform = 3
+)
+
+// VP8L prefix code alphabets, in the order of each prefix code group.
+const (
+	vp8lGreen = iota
+	vp8lRed
+	vp8lBlue
+	vp8lAlpha
+	vp8lDistance
+	vp8lCodesPerGroup
+)
+
+// vp8lLengthCodes counts the backward reference length prefixes, following the green literals.
+const vp8lLengthCodes = 24
+
+// vp8lDistanceCodes counts the backward reference distance prefixes.
+const vp8lDistanceCodes = 40
+
+// vp8lMaxCodeLength limits prefix code lengths.
+const vp8lMaxCodeLength = 15
+
+// vp8lCodeLengthOrder orders the code length code lengths.
+var vp8lCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// vp8lPlaneOffsets maps short distance codes to (x, y) offsets, back and up from the current pixel.
+var vp8lPlaneOffsets = [120][2]int{
+	{0, 1}, {1, 0}, {1, 1}, {-1, 1}, {0, 2}, {2, 0}, {1, 2}, {-1, 2},
+	{2, 1}, {-2, 1}, {2, 2}, {-2, 2}, {0, 3}, {3, 0}, {1, 3}, {-1, 3},
+	{3, 1}, {-3, 1}, {2, 3}, {-2, 3}, {3, 2}, {-3, 2}, {0, 4}, {4, 0},
+	{1, 4}, {-1, 4}, {4, 1}, {-4, 1}, {3, 3}, {-3, 3}, {2, 4}, {-2, 4},
+	{4, 2}, {-4, 2}, {0, 5}, {3, 4}, {-3, 4}, {4, 3}, {-4, 3}, {5, 0},
+	{1, 5}, {-1, 5}, {5, 1}, {-5, 1}, {2, 5}, {-2, 5}, {5, 2}, {-5, 2},
+	{4, 4}, {-4, 4}, {3, 5}, {-3, 5}, {5, 3}, {-5, 3}, {0, 6}, {6, 0},
+	{1, 6}, {-1, 6}, {6, 1}, {-6, 1}, {2, 6}, {-2, 6}, {6, 2}, {-6, 2},
+	{4, 5}, {-4, 5}, {5, 4}, {-5, 4}, {3, 6}, {-3, 6}, {6, 3}, {-6, 3},
+	{0, 7}, {7, 0}, {1, 7}, {-1, 7}, {5, 5}, {-5, 5}, {7, 1}, {-7, 1},
+	{4, 6}, {-4, 6}, {6, 4}, {-6, 4}, {2, 7}, {-2, 7}, {7, 2}, {-7, 2},
+	{3, 7}, {-3, 7}, {7, 3}, {-7, 3}, {5, 6}, {-5, 6}, {6, 5}, {-6, 5},
+	{8, 0}, {4, 7}, {-4, 7}, {7, 4}, {-7, 4}, {8, 1}, {8, 2}, {6, 6},
+	{-6, 6}, {8, 3}, {5, 7}, {-5, 7}, {7, 5}, {-7, 5}, {8, 4}, {6, 7},
+	{-6, 7}, {7, 6}, {-7, 6}, {8, 5}, {7, 7}, {-7, 7}, {8, 6}, {8, 7},
+}
+
+// vp8lBitReader reads VP8L bits, least significant first.
+type vp8lBitReader struct {
+	data  []byte
+	pos   int
+	bits  uint64
+	nbits uint
+	err   error
+}
+
+// fill buffers at least n bits, padding with zeros past the end of the data.
+func (o *vp8lBitReader) fill(n uint) {
+	for o.nbits < n {
+		var b byte
+
+		if o.pos < len(o.data) {
+			b = o.data[o.pos]
+		}
+
+		o.bits |= uint64(b) << o.nbits
+		o.pos++
+		o.nbits += 8
+	}
+}
+
+// peek queries the next n bits without consuming them.
+func (o *vp8lBitReader) peek(n uint) uint32 {
+	o.fill(n)
+	return uint32(o.bits & (1<<n - 1))
+}
+
+// skip consumes n bits, noting reads past the end of the data.
+func (o *vp8lBitReader) skip(n uint) {
+	o.fill(n)
+	o.bits >>= n
+	o.nbits -= n
+
+	if o.err == nil && o.pos > len(o.data) && (o.pos-len(o.data))*8 > int(o.nbits) {
+		o.err = fmt.Errorf("%w: truncated VP8L data", ErrInvalidWebP)
+	}
+}
+
+// read consumes n bits.
+func (o *vp8lBitReader) read(n uint) uint32 {
+	v := o.peek(n)
+	o.skip(n)
+	return v
+}
+
+// vp8lHuffmanRootBits sizes the prefix code lookup table.
+const vp8lHuffmanRootBits = 8
+
+// vp8lHuffman decodes a canonical prefix code.
+type vp8lHuffman struct {
+	// table maps the next root bits of input to a symbol<<4 | length, for codes no longer than the root bits.
+	table [1 << vp8lHuffmanRootBits]uint32
+
+	// counts tallies codes by length.
+	counts [vp8lMaxCodeLength + 1]int
+
+	// symbols lists symbols in canonical code order.
+	symbols []int
+
+	// single denotes the only symbol of a zero length code, or else -1.
+	single int
+}
+
+// newVP8LHuffman builds a prefix code from code lengths.
+func newVP8LHuffman(lengths []uint8) (*vp8lHuffman, error) {
+	h := vp8lHuffman{single: -1}
+	used := 0
+
+	for symbol, length := range lengths {
+		if length > 0 {
+			h.counts[length]++
+			used++
+			h.single = symbol
+		}
+	}
+
+	switch used {
+	case 0:
+		return nil, fmt.Errorf("%w: empty prefix code", ErrInvalidWebP)
+	case 1:
+		// A lone symbol consumes no bits.
+		return &h, nil
+	}
+
+	h.single = -1
+	left := 1
+
+	for length := 1; length <= vp8lMaxCodeLength; length++ {
+		left = left<<1 - h.counts[length]
+
+		if left < 0 {
+			return nil, fmt.Errorf("%w: oversubscribed prefix code", ErrInvalidWebP)
+		}
+	}
+
+	if left != 0 {
+		return nil, fmt.Errorf("%w: incomplete prefix code", ErrInvalidWebP)
+	}
+
+	var offsets [vp8lMaxCodeLength + 2]int
+
+	for length := 1; length <= vp8lMaxCodeLength; length++ {
+		offsets[length+1] = offsets[length] + h.counts[length]
+	}
+
+	h.symbols = make([]int, used)
+	codes := make([]uint32, len(lengths))
+	var next [vp8lMaxCodeLength + 1]uint32
+	var code uint32
+
+	for length := 1; length <= vp8lMaxCodeLength; length++ {
+		code = (code + uint32(h.counts[length-1])) << 1
+		next[length] = code
+	}
+
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+
+		h.symbols[offsets[length]] = symbol
+		offsets[length]++
+		codes[symbol] = next[length]
+		next[length]++
+
+		if length <= vp8lHuffmanRootBits {
+			reversed := reverseBits(codes[symbol], uint(length))
+
+			for i := reversed; i < 1<<vp8lHuffmanRootBits; i += 1 << length {
+				h.table[i] = uint32(symbol)<<4 | uint32(length)
+			}
+		}
+	}
+
+	return &h, nil
+}
+
+// reverseBits reverses the low n bits of a code.
+func reverseBits(code uint32, n uint) uint32 {
+	var reversed uint32
+
+	for i := uint(0); i < n; i++ {
+		reversed = reversed<<1 | code>>i&1
+	}
+
+	return reversed
+}
+
+// decode reads a symbol.
+func (o *vp8lHuffman) decode(r *vp8lBitReader) int {
+	if o.single >= 0 {
+		return o.single
+	}
+
+	if entry := o.table[r.peek(vp8lHuffmanRootBits)]; entry != 0 {
+		r.skip(uint(entry & 0xF))
+		return int(entry >> 4)
+	}
+
+	// Long codes decode bit by bit.
+	code, first, index := 0, 0, 0
+
+	for length := 1; length <= vp8lMaxCodeLength; length++ {
+		code |= int(r.read(1))
+		count := o.counts[length]
+
+		if code-first < count {
+			return o.symbols[index+code-first]
+		}
+
+		index += count
+		first = (first + count) << 1
+		code <<= 1
+	}
+
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: invalid prefix code", ErrInvalidWebP)
+	}
+
+	return 0
+}
+
+// vp8lTransform models a VP8L image transform.
+type vp8lTransform struct {
+	kind  uint32
+	bits  uint
+	width int
+	data  []uint32
+}
+
+// vp8lDecoder decodes a VP8L bitstream.
+type vp8lDecoder struct {
+	r *vp8lBitReader
+}
+
+// decodeVP8L decodes a VP8L bitstream, as stored in a VP8L chunk.
+func decodeVP8L(data []byte) (*image.NRGBA, error) {
+	r := &vp8lBitReader{data: data}
+
+	if r.read(8) != vp8lSignature {
+		return nil, fmt.Errorf("%w: bad VP8L signature", ErrInvalidWebP)
+	}
+
+	width, height := int(r.read(14))+1, int(r.read(14))+1
+	r.skip(1)
+
+	if version := r.read(3); version != 0 {
+		return nil, fmt.Errorf("%w: VP8L version %d", ErrUnsupportedWebP, version)
+	}
+
+	d := vp8lDecoder{r: r}
+	pixels, err := d.decodeImageStream(width, height, true)
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for i, argb := range pixels {
+		img.Pix[4*i] = uint8(argb >> 16)
+		img.Pix[4*i+1] = uint8(argb >> 8)
+		img.Pix[4*i+2] = uint8(argb)
+		img.Pix[4*i+3] = uint8(argb >> 24)
+	}
+
+	return img, nil
+}
+
+// subsampleSize divides a dimension into blocks of 1<<bits, rounding up.
+func subsampleSize(size int, bits uint) int {
+	return (size + 1<<bits - 1) >> bits
+}
+
+// decodeImageStream decodes an entropy coded image.
+//
+// The main image alone carries transforms and meta prefix codes.
+func (o *vp8lDecoder) decodeImageStream(width, height int, main bool) ([]uint32, error) {
+	var transforms []vp8lTransform
+	codedWidth := width
+
+	if main {
+		seen := make(map[uint32]bool)
+
+		for o.r.read(1) == 1 {
+			kind := o.r.read(2)
+
+			if seen[kind] {
+				return nil, fmt.Errorf("%w: repeated VP8L transform", ErrInvalidWebP)
+			}
+
+			seen[kind] = true
+			transform := vp8lTransform{kind: kind, width: codedWidth}
+
+			switch kind {
+			case vp8lPredictorTransform, vp8lColorTransform:
+				transform.bits = uint(o.r.read(3)) + 2
+				data, err := o.decodeImageStream(subsampleSize(codedWidth, transform.bits), subsampleSize(height, transform.bits), false)
+				if err != nil {
+					return nil, err
+				}
+
+				transform.data = data
+			case vp8lColorIndexingTransform:
+				colors := int(o.r.read(8)) + 1
+				data, err := o.decodeImageStream(colors, 1, false)
+				if err != nil {
+					return nil, err
+				}
+
+				for i := 1; i < len(data); i++ {
+					data[i] = addPixels(data[i], data[i-1])
+				}
+
+				// Indices beyond the palette denote transparent black.
+				transform.data = make([]uint32, 256)
+				copy(transform.data, data)
+
+				switch {
+				case colors <= 2:
+					transform.bits = 3
+				case colors <= 4:
+					transform.bits = 2
+				case colors <= 16:
+					transform.bits = 1
+				}
+
+				codedWidth = subsampleSize(codedWidth, transform.bits)
+			}
+
+			transforms = append(transforms, transform)
+		}
+	}
+
+	cacheBits := uint(0)
+
+	if o.r.read(1) == 1 {
+		cacheBits = uint(o.r.read(4))
+
+		if cacheBits < 1 || cacheBits > 11 {
+			return nil, fmt.Errorf("%w: color cache size %d", ErrInvalidWebP, cacheBits)
+		}
+	}
+
+	var metaBits uint
+	var metaCodes []uint32
+	groupCount := 1
+
+	if main && o.r.read(1) == 1 {
+		metaBits = uint(o.r.read(3)) + 2
+		entropyImage, err := o.decodeImageStream(subsampleSize(codedWidth, metaBits), subsampleSize(height, metaBits), false)
+		if err != nil {
+			return nil, err
+		}
+
+		metaCodes = make([]uint32, len(entropyImage))
+
+		for i, argb := range entropyImage {
+			metaCodes[i] = argb >> 8 & 0xFFFF
+			groupCount = max(groupCount, int(metaCodes[i])+1)
+		}
+	}
+
+	if o.r.err != nil {
+		return nil, o.r.err
+	}
+
+	groups := make([][vp8lCodesPerGroup]*vp8lHuffman, groupCount)
+	alphabets := [vp8lCodesPerGroup]int{256 + vp8lLengthCodes, 256, 256, 256, vp8lDistanceCodes}
+
+	if cacheBits > 0 {
+		alphabets[vp8lGreen] += 1 << cacheBits
+	}
+
+	for i := range groups {
+		for j, alphabet := range alphabets {
+			h, err := o.readHuffman(alphabet)
+			if err != nil {
+				return nil, err
+			}
+
+			groups[i][j] = h
+		}
+	}
+
+	pixels, err := o.decodePixels(codedWidth, height, groups, metaBits, metaCodes, cacheBits)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(transforms) - 1; i >= 0; i-- {
+		pixels = transforms[i].invert(pixels, height)
+	}
+
+	return pixels, nil
+}
+
+// readHuffman reads a prefix code for an alphabet.
+func (o *vp8lDecoder) readHuffman(alphabet int) (*vp8lHuffman, error) {
+	lengths := make([]uint8, alphabet)
+
+	if o.r.read(1) == 1 {
+		// Simple code of one or two symbols.
+		symbols := int(o.r.read(1)) + 1
+		firstBits := uint(1)
+
+		if o.r.read(1) == 1 {
+			firstBits = 8
+		}
+
+		first := int(o.r.read(firstBits))
+
+		if first >= alphabet {
+			return nil, fmt.Errorf("%w: prefix code symbol %d", ErrInvalidWebP, first)
+		}
+
+		lengths[first] = 1
+
+		if symbols == 2 {
+			second := int(o.r.read(8))
+
+			if second >= alphabet {
+				return nil, fmt.Errorf("%w: prefix code symbol %d", ErrInvalidWebP, second)
+			}
+
+			lengths[second] = 1
+		}
+
+		return newVP8LHuffman(lengths)
+	}
+
+	var codeLengthLengths [19]uint8
+	count := int(o.r.read(4)) + 4
+
+	for i := 0; i < count; i++ {
+		codeLengthLengths[vp8lCodeLengthOrder[i]] = uint8(o.r.read(3))
+	}
+
+	codeLengthCode, err := newVP8LHuffman(codeLengthLengths[:])
+	if err != nil {
+		return nil, err
+	}
+
+	limit := alphabet
+
+	if o.r.read(1) == 1 {
+		limitBits := 2 + 2*uint(o.r.read(3))
+		limit = 2 + int(o.r.read(limitBits))
+
+		if limit > alphabet {
+			return nil, fmt.Errorf("%w: prefix code length count %d", ErrInvalidWebP, limit)
+		}
+	}
+
+	previous := uint8(8)
+
+	for symbol := 0; symbol < alphabet && limit > 0; limit-- {
+		length := codeLengthCode.decode(o.r)
+
+		if length < 16 {
+			lengths[symbol] = uint8(length)
+			symbol++
+
+			if length != 0 {
+				previous = uint8(length)
+			}
+
+			continue
+		}
+
+		var repeat int
+		var value uint8
+
+		switch length {
+		case 16:
+			repeat, value = 3+int(o.r.read(2)), previous
+		case 17:
+			repeat = 3 + int(o.r.read(3))
+		default:
+			repeat = 11 + int(o.r.read(7))
+		}
+
+		if symbol+repeat > alphabet {
+			return nil, fmt.Errorf("%w: prefix code lengths overflow", ErrInvalidWebP)
+		}
+
+		for ; repeat > 0; repeat-- {
+			lengths[symbol] = value
+			symbol++
+		}
+	}
+
+	if o.r.err != nil {
+		return nil, o.r.err
+	}
+
+	return newVP8LHuffman(lengths)
+}
+
+// vp8lPrefixValue decodes a length or distance from its prefix and extra bits.
+func vp8lPrefixValue(prefix int, r *vp8lBitReader) int {
+	if prefix < 4 {
+		return prefix + 1
+	}
+
+	extraBits := uint(prefix-2) >> 1
+	offset := (2 + prefix&1) << extraBits
+	return offset + int(r.read(extraBits)) + 1
+}
+
+// vp8lColorCacheIndex hashes a color into a color cache.
+func vp8lColorCacheIndex(argb uint32, cacheBits uint) uint32 {
+	return (0x1E35A7BD * argb) >> (32 - cacheBits)
+}
+
+// decodePixels decodes entropy coded ARGB pixels.
+func (o *vp8lDecoder) decodePixels(width, height int, groups [][vp8lCodesPerGroup]*vp8lHuffman, metaBits uint, metaCodes []uint32, cacheBits uint) ([]uint32, error) {
+	pixels := make([]uint32, width*height)
+	var cache []uint32
+
+	if cacheBits > 0 {
+		cache = make([]uint32, 1<<cacheBits)
+	}
+
+	metaWidth := subsampleSize(width, metaBits)
+	cached := 0
+
+	for pos := 0; pos < len(pixels); {
+		if o.r.err != nil {
+			return nil, o.r.err
+		}
+
+		group := &groups[0]
+
+		if metaCodes != nil {
+			x, y := pos%width, pos/width
+			group = &groups[metaCodes[(y>>metaBits)*metaWidth+x>>metaBits]]
+		}
+
+		symbol := group[vp8lGreen].decode(o.r)
+
+		switch {
+		case symbol < 256:
+			red := uint32(group[vp8lRed].decode(o.r))
+			blue := uint32(group[vp8lBlue].decode(o.r))
+			alpha := uint32(group[vp8lAlpha].decode(o.r))
+			pixels[pos] = alpha<<24 | red<<16 | uint32(symbol)<<8 | blue
+			pos++
+		case symbol < 256+vp8lLengthCodes:
+			length := vp8lPrefixValue(symbol-256, o.r)
+			distance := vp8lPlaneDistance(width, vp8lPrefixValue(group[vp8lDistance].decode(o.r), o.r))
+
+			if distance > pos || length > len(pixels)-pos {
+				return nil, fmt.Errorf("%w: backward reference out of bounds", ErrInvalidWebP)
+			}
+
+			for end := pos + length; pos < end; pos++ {
+				pixels[pos] = pixels[pos-distance]
+			}
+		default:
+			index := symbol - 256 - vp8lLengthCodes
+
+			if index >= len(cache) {
+				return nil, fmt.Errorf("%w: color cache index %d", ErrInvalidWebP, index)
+			}
+
+			for ; cached < pos; cached++ {
+				cache[vp8lColorCacheIndex(pixels[cached], cacheBits)] = pixels[cached]
+			}
+
+			pixels[pos] = cache[index]
+			pos++
+		}
+
+		if cache != nil {
+			for ; cached < pos; cached++ {
+				cache[vp8lColorCacheIndex(pixels[cached], cacheBits)] = pixels[cached]
+			}
+		}
+	}
+
+	if o.r.err != nil {
+		return nil, o.r.err
+	}
+
+	return pixels, nil
+}
+
+// vp8lPlaneDistance maps a distance code to a pixel distance,
+// with codes up to 120 denoting nearby pixels in two dimensions.
+func vp8lPlaneDistance(width, code int) int {
+	if code > len(vp8lPlaneOffsets) {
+		return code - len(vp8lPlaneOffsets)
+	}
+
+	offset := vp8lPlaneOffsets[code-1]
+	return max(offset[0]+offset[1]*width, 1)
+}
+
+// addPixels adds ARGB pixels channel by channel, modulo 256.
+func addPixels(a, b uint32) uint32 {
+	return (a&0xFF00FF00+b&0xFF00FF00)&0xFF00FF00 | (a&0x00FF00FF+b&0x00FF00FF)&0x00FF00FF
+}
+
+// subPixels subtracts ARGB pixels channel by channel, modulo 256.
+func subPixels(a, b uint32) uint32 {
+	return (0x00FF00FF+a&0xFF00FF00-b&0xFF00FF00)&0xFF00FF00 | (0xFF00FF00+a&0x00FF00FF-b&0x00FF00FF)&0x00FF00FF
+}
+
+// invert undoes the transform, returning pixels of the transform width.
+func (o vp8lTransform) invert(pixels []uint32, height int) []uint32 {
+	switch o.kind {
+	case vp8lPredictorTransform:
+		blockWidth := subsampleSize(o.width, o.bits)
+
+		for y := 0; y < height; y++ {
+			for x := 0; x < o.width; x++ {
+				i := y*o.width + x
+				var predicted uint32
+
+				switch {
+				case y == 0 && x == 0:
+					predicted = 0xFF000000
+				case y == 0:
+					predicted = pixels[i-1]
+				case x == 0:
+					predicted = pixels[i-o.width]
+				default:
+					mode := o.data[(y>>o.bits)*blockWidth+x>>o.bits] >> 8 & 0xF
+					predicted = vp8lPredict(mode, pixels, i, o.width)
+				}
+
+				pixels[i] = addPixels(pixels[i], predicted)
+			}
+		}
+
+		return pixels
+	case vp8lColorTransform:
+		blockWidth := subsampleSize(o.width, o.bits)
+
+		for y := 0; y < height; y++ {
+			for x := 0; x < o.width; x++ {
+				i := y*o.width + x
+				element := o.data[(y>>o.bits)*blockWidth+x>>o.bits]
+				pixels[i] = invertColorTransform(pixels[i], element)
+			}
+		}
+
+		return pixels
+	case vp8lSubtractGreenTransform:
+		for i, argb := range pixels {
+			green := argb >> 8 & 0xFF
+			pixels[i] = addPixels(argb, green<<16|green)
+		}
+
+		return pixels
+	default:
+		codedWidth := subsampleSize(o.width, o.bits)
+		perPixel := uint(8) >> o.bits
+		mask := uint32(1)<<perPixel - 1
+		expanded := make([]uint32, o.width*height)
+
+		for y := 0; y < height; y++ {
+			for x := 0; x < o.width; x++ {
+				packed := pixels[y*codedWidth+x>>o.bits] >> 8 & 0xFF
+				index := packed >> (uint(x&(1<<o.bits-1)) * perPixel) & mask
+				expanded[y*o.width+x] = o.data[index]
+			}
+		}
+
+		return expanded
+	}
+}
+
+// colorTransformDelta scales a color by a transform multiplier.
+func colorTransformDelta(multiplier, c uint32) uint32 {
+	return uint32(int32(int8(multiplier)) * int32(int8(c)) >> 5)
+}
+
+// invertColorTransform undoes the color transform of a pixel.
+func invertColorTransform(argb, element uint32) uint32 {
+	green := argb >> 8
+	red := (argb>>16 + colorTransformDelta(element, green)) & 0xFF
+	blue := argb + colorTransformDelta(element>>8, green)
+	blue = (blue + colorTransformDelta(element>>16, red)) & 0xFF
+	return argb&0xFF00FF00 | red<<16 | blue
+}
+
+// vp8lPredict computes a pixel prediction from its decoded neighbors.
+//
+// The pixel lies past the first row and column.
+func vp8lPredict(mode uint32, pixels []uint32, i, width int) uint32 {
+	left, top, topLeft := pixels[i-1], pixels[i-width], pixels[i-width-1]
+
+	// The top right neighbor of the last column wraps to the first pixel of the current row.
+	topRight := pixels[i-width+1]
+
+	switch mode {
+	case 1:
+		return left
+	case 2:
+		return top
+	case 3:
+		return topRight
+	case 4:
+		return topLeft
+	case 5:
+		return averagePixels(averagePixels(left, topRight), top)
+	case 6:
+		return averagePixels(left, topLeft)
+	case 7:
+		return averagePixels(left, top)
+	case 8:
+		return averagePixels(topLeft, top)
+	case 9:
+		return averagePixels(top, topRight)
+	case 10:
+		return averagePixels(averagePixels(left, topLeft), averagePixels(top, topRight))
+	case 11:
+		return selectPixel(left, top, topLeft)
+	case 12:
+		return clampAddSubtractFull(left, top, topLeft)
+	case 13:
+		return clampAddSubtractHalf(averagePixels(left, top), topLeft)
+	default:
+		return 0xFF000000
+	}
+}
+
+// averagePixels averages ARGB pixels channel by channel, rounding down.
+func averagePixels(a, b uint32) uint32 {
+	return (((a ^ b) & 0xFEFEFEFE) >> 1) + (a & b)
+}
+
+// channel extracts an ARGB channel.
+func channel(argb uint32, shift uint) int {
+	return int(argb >> shift & 0xFF)
+}
+
+// selectPixel chooses the left or top pixel, whichever lies nearer the gradient estimate.
+func selectPixel(left, top, topLeft uint32) uint32 {
+	var leftDistance, topDistance int
+
+	for shift := uint(0); shift < 32; shift += 8 {
+		estimate := channel(left, shift) + channel(top, shift) - channel(topLeft, shift)
+		leftDistance += absInt(estimate - channel(left, shift))
+		topDistance += absInt(estimate - channel(top, shift))
+	}
+
+	if leftDistance < topDistance {
+		return left
+	}
+
+	return top
+}
+
+// clampAddSubtractFull predicts a + b - c, channel by channel.
+func clampAddSubtractFull(a, b, c uint32) uint32 {
+	var argb uint32
+
+	for shift := uint(0); shift < 32; shift += 8 {
+		argb |= uint32(min(max(channel(a, shift)+channel(b, shift)-channel(c, shift), 0), 0xFF)) << shift
+	}
+
+	return argb
+}
+
+// clampAddSubtractHalf predicts a + (a - b) / 2, channel by channel.
+func clampAddSubtractHalf(a, b uint32) uint32 {
+	var argb uint32
+
+	for shift := uint(0); shift < 32; shift += 8 {
+		argb |= uint32(min(max(channel(a, shift)+(channel(a, shift)-channel(b, shift))/2, 0), 0xFF)) << shift
+	}
+
+	return argb
+}
@@ -0,0 +1,699 @@
+package buttery
+
+import (
+	"fmt"
+	"image"
+	"math/bits"
+	"slices"
+)
+
+// vp8lMaxSize limits VP8L image dimensions.
+const vp8lMaxSize = 1 << 14
+
+// vp8lPredictorBits sizes the blocks sharing a predictor mode, 16x16.
+const vp8lPredictorBits = 4
+
+// vp8lPredictorModes counts the predictor modes.
+const vp8lPredictorModes = 14
+
+// vp8lMinMatch denotes the shortest backward reference worth encoding.
+const vp8lMinMatch = 3
+
+// vp8lMaxMatch denotes the longest backward reference.
+const vp8lMaxMatch = 4096
+
+// vp8lMaxDistance denotes the farthest backward reference.
+const vp8lMaxDistance = 1<<20 - len(vp8lPlaneOffsets)
+
+// vp8lMatchChain limits the candidates searched per pixel.
+const vp8lMatchChain = 32
+
+// vp8lHashBits sizes the backward reference hash table.
+const vp8lHashBits = 16
+
+// vp8lBitWriter writes VP8L bits, least significant first.
+type vp8lBitWriter struct {
+	buf   []byte
+	bits  uint64
+	nbits uint
+}
+
+// write appends the low n bits of a value.
+func (o *vp8lBitWriter) write(v uint32, n uint) {
+	o.bits |= uint64(v) << o.nbits
+	o.nbits += n
+
+	for o.nbits >= 8 {
+		o.buf = append(o.buf, byte(o.bits))
+		o.bits >>= 8
+		o.nbits -= 8
+	}
+}
+
+// bytes flushes any partial byte, returning the bitstream.
+func (o *vp8lBitWriter) bytes() []byte {
+	if o.nbits > 0 {
+		o.buf = append(o.buf, byte(o.bits))
+		o.bits, o.nbits = 0, 0
+	}
+
+	return o.buf
+}
+
+// vp8lCode models a prefix code for writing.
+type vp8lCode struct {
+	// codes holds bit reversed codes, ready for writing least significant bit first.
+	codes []uint32
+
+	// lengths holds the emitted length of each code, zero throughout for a lone symbol.
+	lengths []uint8
+}
+
+// put writes a symbol.
+func (o *vp8lCode) put(w *vp8lBitWriter, symbol int) {
+	w.write(o.codes[symbol], uint(o.lengths[symbol]))
+}
+
+// newVP8LCode assigns canonical codes to code lengths.
+func newVP8LCode(lengths []uint8) *vp8lCode {
+	c := vp8lCode{codes: make([]uint32, len(lengths)), lengths: slices.Clone(lengths)}
+	var counts [vp8lMaxCodeLength + 1]uint32
+	used := 0
+
+	for _, length := range lengths {
+		if length > 0 {
+			counts[length]++
+			used++
+		}
+	}
+
+	if used < 2 {
+		// Lone symbols consume no bits.
+		clear(c.lengths)
+		return &c
+	}
+
+	var next [vp8lMaxCodeLength + 1]uint32
+	var code uint32
+
+	for length := 1; length <= vp8lMaxCodeLength; length++ {
+		code = (code + counts[length-1]) << 1
+		next[length] = code
+	}
+
+	for symbol, length := range lengths {
+		if length > 0 {
+			c.codes[symbol] = reverseBits(next[length], uint(length))
+			next[length]++
+		}
+	}
+
+	return &c
+}
+
+// huffmanLengths computes prefix code lengths no longer than a limit for symbol frequencies.
+//
+// Lone symbols receive length 1.
+func huffmanLengths(freqs []int, limit int) []uint8 {
+	lengths := make([]uint8, len(freqs))
+	var used []int
+
+	for symbol, freq := range freqs {
+		if freq > 0 {
+			used = append(used, symbol)
+		}
+	}
+
+	switch len(used) {
+	case 0:
+		return lengths
+	case 1:
+		lengths[used[0]] = 1
+		return lengths
+	}
+
+	weights := make([]int, len(freqs))
+	copy(weights, freqs)
+
+	// Flattening the distribution shortens the deepest codes, until they fit the limit.
+	for floor := 1; ; floor *= 2 {
+		for _, symbol := range used {
+			weights[symbol] = max(weights[symbol], floor)
+		}
+
+		if huffmanDepths(used, weights, lengths) <= limit {
+			return lengths
+		}
+	}
+}
+
+// huffmanDepths builds a Huffman tree over the used symbols,
+// storing leaf depths as code lengths and returning the deepest.
+func huffmanDepths(used []int, weights []int, lengths []uint8) int {
+	type node struct {
+		weight int
+		parent int
+	}
+
+	leaves := slices.Clone(used)
+	slices.SortStableFunc(leaves, func(a, b int) int { return weights[a] - weights[b] })
+	nodes := make([]node, len(leaves), 2*len(leaves)-1)
+
+	for i, symbol := range leaves {
+		nodes[i] = node{weight: weights[symbol], parent: -1}
+	}
+
+	// Two queues: sorted leaves, then internal nodes in creation order, which are also sorted.
+	nextLeaf, nextInternal := 0, len(leaves)
+	pop := func() int {
+		if nextLeaf < len(leaves) && (nextInternal >= len(nodes) || nodes[nextLeaf].weight <= nodes[nextInternal].weight) {
+			nextLeaf++
+			return nextLeaf - 1
+		}
+
+		nextInternal++
+		return nextInternal - 1
+	}
+
+	for len(nodes) < 2*len(leaves)-1 {
+		a, b := pop(), pop()
+		nodes = append(nodes, node{weight: nodes[a].weight + nodes[b].weight, parent: -1})
+		nodes[a].parent, nodes[b].parent = len(nodes)-1, len(nodes)-1
+	}
+
+	depths := make([]int, len(nodes))
+	deepest := 0
+
+	for i := len(nodes) - 2; i >= 0; i-- {
+		depths[i] = depths[nodes[i].parent] + 1
+	}
+
+	for i, symbol := range leaves {
+		lengths[symbol] = uint8(min(depths[i], 0xFF))
+		deepest = max(deepest, depths[i])
+	}
+
+	return deepest
+}
+
+// writeHuffman writes a prefix code for symbol frequencies, returning the code.
+func writeHuffman(w *vp8lBitWriter, freqs []int) *vp8lCode {
+	lengths := huffmanLengths(freqs, vp8lMaxCodeLength)
+	var used []int
+
+	for symbol, length := range lengths {
+		if length > 0 {
+			used = append(used, symbol)
+		}
+	}
+
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < 256) {
+		// Simple code.
+		w.write(1, 1)
+
+		if len(used) == 0 {
+			used = append(used, 0)
+			lengths[0] = 1
+		}
+
+		w.write(uint32(len(used)-1), 1)
+
+		if used[0] < 2 {
+			w.write(0, 1)
+			w.write(uint32(used[0]), 1)
+		} else {
+			w.write(1, 1)
+			w.write(uint32(used[0]), 8)
+		}
+
+		if len(used) == 2 {
+			w.write(uint32(used[1]), 8)
+		}
+
+		return newVP8LCode(lengths)
+	}
+
+	w.write(0, 1)
+	writeCodeLengths(w, lengths)
+	return newVP8LCode(lengths)
+}
+
+// vp8lCodeLengthToken models a run length coded code length.
+type vp8lCodeLengthToken struct {
+	symbol int
+	extra  uint32
+}
+
+// vp8lCodeLengthExtraBits sizes the extra bits of code length repeat symbols 16, 17, and 18.
+var vp8lCodeLengthExtraBits = [3]uint{2, 3, 7}
+
+// writeCodeLengths writes normal prefix code lengths, run length coded.
+func writeCodeLengths(w *vp8lBitWriter, lengths []uint8) {
+	var tokens []vp8lCodeLengthToken
+	previous := uint8(8)
+
+	for i := 0; i < len(lengths); {
+		length := lengths[i]
+		run := 1
+
+		for i+run < len(lengths) && lengths[i+run] == length {
+			run++
+		}
+
+		i += run
+
+		if length == 0 {
+			for ; run >= 11; run -= min(run, 138) {
+				tokens = append(tokens, vp8lCodeLengthToken{symbol: 18, extra: uint32(min(run, 138) - 11)})
+			}
+
+			if run >= 3 {
+				tokens = append(tokens, vp8lCodeLengthToken{symbol: 17, extra: uint32(run - 3)})
+				run = 0
+			}
+
+			for ; run > 0; run-- {
+				tokens = append(tokens, vp8lCodeLengthToken{symbol: 0})
+			}
+
+			continue
+		}
+
+		if length != previous {
+			tokens = append(tokens, vp8lCodeLengthToken{symbol: int(length)})
+			previous = length
+			run--
+		}
+
+		for ; run >= 3; run -= min(run, 6) {
+			tokens = append(tokens, vp8lCodeLengthToken{symbol: 16, extra: uint32(min(run, 6) - 3)})
+		}
+
+		for ; run > 0; run-- {
+			tokens = append(tokens, vp8lCodeLengthToken{symbol: int(length)})
+		}
+	}
+
+	freqs := make([]int, len(vp8lCodeLengthOrder))
+
+	for _, token := range tokens {
+		freqs[token.symbol]++
+	}
+
+	codeLengthLengths := huffmanLengths(freqs, 7)
+	count := 4
+
+	for i, symbol := range vp8lCodeLengthOrder {
+		if codeLengthLengths[symbol] > 0 {
+			count = max(count, i+1)
+		}
+	}
+
+	w.write(uint32(count-4), 4)
+
+	for _, symbol := range vp8lCodeLengthOrder[:count] {
+		w.write(uint32(codeLengthLengths[symbol]), 3)
+	}
+
+	// Code lengths span the whole alphabet.
+	w.write(0, 1)
+	codeLengthCode := newVP8LCode(codeLengthLengths)
+
+	for _, token := range tokens {
+		codeLengthCode.put(w, token.symbol)
+
+		if token.symbol >= 16 {
+			w.write(token.extra, vp8lCodeLengthExtraBits[token.symbol-16])
+		}
+	}
+}
+
+// vp8lPrefix encodes a length or distance as a prefix and extra bits, see vp8lPrefixValue.
+func vp8lPrefix(value int) (int, uint, uint32) {
+	value--
+
+	if value < 4 {
+		return value, 0, 0
+	}
+
+	highest := bits.Len(uint(value)) - 1
+	extraBits := uint(highest - 1)
+	prefix := 2*highest + value>>extraBits&1
+	return prefix, extraBits, uint32(value) & (1<<extraBits - 1)
+}
+
+// vp8lToken models a literal pixel, or a backward reference when length is positive.
+type vp8lToken struct {
+	argb     uint32
+	length   int
+	distance int
+}
+
+// vp8lDistanceCode maps a pixel distance to a distance code,
+// using the two dimensional codes for the left and upper neighbors.
+func vp8lDistanceCode(width, distance int) int {
+	switch distance {
+	case width:
+		return 1
+	case 1:
+		return 2
+	default:
+		return distance + len(vp8lPlaneOffsets)
+	}
+}
+
+// vp8lHash hashes the pair of pixels starting at an index.
+func vp8lHash(pixels []uint32, i int) uint32 {
+	return (pixels[i]*0x9E3779B1 ^ pixels[i+1]*0x85EBCA6B) >> (32 - vp8lHashBits)
+}
+
+// matchLength counts the pixels matching between two positions, up to a limit.
+func matchLength(pixels []uint32, a, b, limit int) int {
+	n := 0
+
+	for n < limit && pixels[a+n] == pixels[b+n] {
+		n++
+	}
+
+	return n
+}
+
+// vp8lTokenize finds backward references, greedily taking the longest match per position.
+func vp8lTokenize(pixels []uint32, width int) []vp8lToken {
+	tokens := make([]vp8lToken, 0, len(pixels))
+	head := make([]int32, 1<<vp8lHashBits)
+	chain := make([]int32, len(pixels))
+
+	for i := range head {
+		head[i] = -1
+	}
+
+	insert := func(i int) {
+		if i+1 < len(pixels) {
+			h := vp8lHash(pixels, i)
+			chain[i] = head[h]
+			head[h] = int32(i)
+		}
+	}
+
+	for i := 0; i < len(pixels); {
+		limit := min(vp8lMaxMatch, len(pixels)-i)
+		bestLength, bestDistance := 0, 0
+
+		// The left and upper neighbors have the cheapest distance codes.
+		for _, distance := range []int{1, width} {
+			if distance <= i {
+				if n := matchLength(pixels, i-distance, i, limit); n > bestLength {
+					bestLength, bestDistance = n, distance
+				}
+			}
+		}
+
+		if i+1 < len(pixels) && bestLength < limit {
+			candidate := head[vp8lHash(pixels, i)]
+
+			for steps := 0; candidate >= 0 && steps < vp8lMatchChain && i-int(candidate) <= vp8lMaxDistance; steps++ {
+				// Prefer nearer candidates on ties.
+				if n := matchLength(pixels, int(candidate), i, limit); n > bestLength {
+					bestLength, bestDistance = n, i-int(candidate)
+				}
+
+				candidate = chain[candidate]
+			}
+		}
+
+		if bestLength < vp8lMinMatch {
+			tokens = append(tokens, vp8lToken{argb: pixels[i]})
+			insert(i)
+			i++
+			continue
+		}
+
+		tokens = append(tokens, vp8lToken{length: bestLength, distance: bestDistance})
+
+		for end := i + bestLength; i < end; i++ {
+			insert(i)
+		}
+	}
+
+	return tokens
+}
+
+// writeImageData entropy codes pixels, without color cache.
+//
+// The main image declares a single prefix code group.
+func writeImageData(w *vp8lBitWriter, pixels []uint32, width int, main bool) {
+	w.write(0, 1)
+
+	if main {
+		w.write(0, 1)
+	}
+
+	tokens := vp8lTokenize(pixels, width)
+	freqs := [vp8lCodesPerGroup][]int{
+		make([]int, 256+vp8lLengthCodes),
+		make([]int, 256),
+		make([]int, 256),
+		make([]int, 256),
+		make([]int, vp8lDistanceCodes),
+	}
+
+	for _, token := range tokens {
+		if token.length == 0 {
+			freqs[vp8lGreen][token.argb>>8&0xFF]++
+			freqs[vp8lRed][token.argb>>16&0xFF]++
+			freqs[vp8lBlue][token.argb&0xFF]++
+			freqs[vp8lAlpha][token.argb>>24]++
+			continue
+		}
+
+		lengthPrefix, _, _ := vp8lPrefix(token.length)
+		distancePrefix, _, _ := vp8lPrefix(vp8lDistanceCode(width, token.distance))
+		freqs[vp8lGreen][256+lengthPrefix]++
+		freqs[vp8lDistance][distancePrefix]++
+	}
+
+	var codes [vp8lCodesPerGroup]*vp8lCode
+
+	for i := range codes {
+		codes[i] = writeHuffman(w, freqs[i])
+	}
+
+	for _, token := range tokens {
+		if token.length == 0 {
+			codes[vp8lGreen].put(w, int(token.argb>>8&0xFF))
+			codes[vp8lRed].put(w, int(token.argb>>16&0xFF))
+			codes[vp8lBlue].put(w, int(token.argb&0xFF))
+			codes[vp8lAlpha].put(w, int(token.argb>>24))
+			continue
+		}
+
+		prefix, extraBits, extra := vp8lPrefix(token.length)
+		codes[vp8lGreen].put(w, 256+prefix)
+		w.write(extra, extraBits)
+		prefix, extraBits, extra = vp8lPrefix(vp8lDistanceCode(width, token.distance))
+		codes[vp8lDistance].put(w, prefix)
+		w.write(extra, extraBits)
+	}
+}
+
+// encodeVP8L encodes an image as a lossless VP8L bitstream.
+//
+// Images of at most 256 colors use a color index transform,
+// while others subtract green and predict each pixel from its neighbors.
+func encodeVP8L(img *image.NRGBA) ([]byte, error) {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+
+	if width < 1 || height < 1 || width > vp8lMaxSize || height > vp8lMaxSize {
+		return nil, fmt.Errorf("%w: VP8L images span 1 to %d pixels, not %dx%d", ErrUnsupportedWebP, vp8lMaxSize, width, height)
+	}
+
+	pixels := make([]uint32, width*height)
+	alpha := uint32(0)
+
+	for y := 0; y < height; y++ {
+		row := img.Pix[y*img.Stride:]
+
+		for x := 0; x < width; x++ {
+			p := row[4*x:]
+			pixels[y*width+x] = uint32(p[3])<<24 | uint32(p[0])<<16 | uint32(p[1])<<8 | uint32(p[2])
+
+			if p[3] != 0xFF {
+				alpha = 1
+			}
+		}
+	}
+
+	w := &vp8lBitWriter{}
+	w.write(vp8lSignature, 8)
+	w.write(uint32(width-1), 14)
+	w.write(uint32(height-1), 14)
+	w.write(alpha, 1)
+	w.write(0, 3)
+
+	if palette := vp8lPalette(pixels); palette != nil {
+		pixels, width = writeColorIndexing(w, pixels, width, height, palette)
+	} else {
+		writeSubtractGreen(w, pixels)
+		writePredictor(w, pixels, width, height)
+	}
+
+	w.write(0, 1)
+	writeImageData(w, pixels, width, true)
+	return w.bytes(), nil
+}
+
+// vp8lPalette collects the colors of an image, or nil beyond 256 colors.
+func vp8lPalette(pixels []uint32) []uint32 {
+	seen := make(map[uint32]bool)
+
+	for _, argb := range pixels {
+		if !seen[argb] {
+			if len(seen) == 256 {
+				return nil
+			}
+
+			seen[argb] = true
+		}
+	}
+
+	palette := make([]uint32, 0, len(seen))
+
+	for argb := range seen {
+		palette = append(palette, argb)
+	}
+
+	slices.Sort(palette)
+	return palette
+}
+
+// writeColorIndexing writes a color indexing transform,
+// returning the bundled index pixels and their width.
+func writeColorIndexing(w *vp8lBitWriter, pixels []uint32, width, height int, palette []uint32) ([]uint32, int) {
+	w.write(1, 1)
+	w.write(vp8lColorIndexingTransform, 2)
+	w.write(uint32(len(palette)-1), 8)
+	deltas := make([]uint32, len(palette))
+	indices := make(map[uint32]uint32, len(palette))
+
+	for i, argb := range palette {
+		deltas[i] = argb
+		indices[argb] = uint32(i)
+
+		if i > 0 {
+			deltas[i] = subPixels(argb, palette[i-1])
+		}
+	}
+
+	writeImageData(w, deltas, len(deltas), false)
+
+	var bundleBits uint
+
+	switch {
+	case len(palette) <= 2:
+		bundleBits = 3
+	case len(palette) <= 4:
+		bundleBits = 2
+	case len(palette) <= 16:
+		bundleBits = 1
+	}
+
+	perPixel := uint(8) >> bundleBits
+	codedWidth := subsampleSize(width, bundleBits)
+	bundled := make([]uint32, codedWidth*height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			shift := uint(x&(1<<bundleBits-1)) * perPixel
+			bundled[y*codedWidth+x>>bundleBits] |= indices[pixels[y*width+x]] << shift << 8
+		}
+	}
+
+	for i := range bundled {
+		bundled[i] |= 0xFF000000
+	}
+
+	return bundled, codedWidth
+}
+
+// writeSubtractGreen writes a subtract green transform, applying it in place.
+func writeSubtractGreen(w *vp8lBitWriter, pixels []uint32) {
+	w.write(1, 1)
+	w.write(vp8lSubtractGreenTransform, 2)
+
+	for i, argb := range pixels {
+		green := argb >> 8 & 0xFF
+		pixels[i] = subPixels(argb, green<<16|green)
+	}
+}
+
+// writePredictor writes a predictor transform, replacing pixels with residuals in place.
+//
+// Each block takes the mode with the smallest residuals.
+func writePredictor(w *vp8lBitWriter, pixels []uint32, width, height int) {
+	w.write(1, 1)
+	w.write(vp8lPredictorTransform, 2)
+	w.write(vp8lPredictorBits-2, 3)
+	blockWidth, blockHeight := subsampleSize(width, vp8lPredictorBits), subsampleSize(height, vp8lPredictorBits)
+	modes := make([]uint32, blockWidth*blockHeight)
+
+	for by := 0; by < blockHeight; by++ {
+		for bx := 0; bx < blockWidth; bx++ {
+			bestMode, bestCost := uint32(0), -1
+
+			for mode := uint32(0); mode < vp8lPredictorModes; mode++ {
+				cost := 0
+
+				for y := by << vp8lPredictorBits; y < min((by+1)<<vp8lPredictorBits, height); y++ {
+					for x := bx << vp8lPredictorBits; x < min((bx+1)<<vp8lPredictorBits, width); x++ {
+						cost += residualCost(subPixels(pixels[y*width+x], predictAt(mode, pixels, x, y, width)))
+					}
+				}
+
+				if bestCost < 0 || cost < bestCost {
+					bestMode, bestCost = mode, cost
+				}
+			}
+
+			modes[by*blockWidth+bx] = 0xFF000000 | bestMode<<8
+		}
+	}
+
+	// Residuals depend on original neighbors, so compute them back to front.
+	for y := height - 1; y >= 0; y-- {
+		for x := width - 1; x >= 0; x-- {
+			mode := modes[(y>>vp8lPredictorBits)*blockWidth+x>>vp8lPredictorBits] >> 8 & 0xF
+			i := y*width + x
+			pixels[i] = subPixels(pixels[i], predictAt(mode, pixels, x, y, width))
+		}
+	}
+
+	writeImageData(w, modes, blockWidth, false)
+}
+
+// predictAt predicts a pixel per a mode, following the edge rules of the predictor transform.
+func predictAt(mode uint32, pixels []uint32, x, y, width int) uint32 {
+	i := y*width + x
+
+	switch {
+	case y == 0 && x == 0:
+		return 0xFF000000
+	case y == 0:
+		return pixels[i-1]
+	case x == 0:
+		return pixels[i-width]
+	default:
+		return vp8lPredict(mode, pixels, i, width)
+	}
+}
+
+// residualCost estimates the cost of coding a residual, as the sum of channel magnitudes.
+func residualCost(argb uint32) int {
+	cost := 0
+
+	for shift := uint(0); shift < 32; shift += 8 {
+		cost += abs8(int8(argb >> shift))
+	}
+
+	return cost
+}
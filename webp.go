@@ -0,0 +1,343 @@
+package buttery
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+)
+
+// webpMaxPixels bounds the canvas area, guarding against corrupt headers.
+const webpMaxPixels = 1 << 28
+
+// WebP VP8X feature flags.
+const (
+	webpAnimationFlag = 0x02
+	webpAlphaFlag     = 0x10
+)
+
+// WebP ANMF frame flags.
+const (
+	webpDisposeFlag = 0x01
+	webpNoBlendFlag = 0x02
+)
+
+// webpChunk models a RIFF chunk.
+type webpChunk struct {
+	kind string
+	data []byte
+}
+
+// readWebPChunks splits RIFF chunk data, honoring padding to even sizes.
+func readWebPChunks(data []byte) ([]webpChunk, error) {
+	var chunks []webpChunk
+
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("%w: truncated chunk header", ErrInvalidWebP)
+		}
+
+		kind, size := string(data[:4]), binary.LittleEndian.Uint32(data[4:])
+
+		if uint64(size) > uint64(len(data)-8) {
+			return nil, fmt.Errorf("%w: truncated %v chunk", ErrInvalidWebP, kind)
+		}
+
+		chunks = append(chunks, webpChunk{kind: kind, data: data[8 : 8+size]})
+		data = data[min(8+int(size)+int(size&1), len(data)):]
+	}
+
+	return chunks, nil
+}
+
+// appendWebPChunk appends a RIFF chunk, padded to an even size.
+func appendWebPChunk(buf []byte, kind string, data []byte) []byte {
+	buf = append(buf, kind...)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(data)))
+	buf = append(buf, data...)
+
+	if len(data)%2 == 1 {
+		buf = append(buf, 0)
+	}
+
+	return buf
+}
+
+// uint24 reads a little endian 24-bit integer.
+func uint24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+}
+
+// appendUint24 appends a little endian 24-bit integer.
+func appendUint24(buf []byte, v int) []byte {
+	return append(buf, byte(v), byte(v>>8), byte(v>>16))
+}
+
+// decodeWebPImage decodes the image chunk of a still image or animation frame.
+func decodeWebPImage(chunks []webpChunk) (*image.NRGBA, error) {
+	for _, chunk := range chunks {
+		switch chunk.kind {
+		case "VP8L":
+			return decodeVP8L(chunk.data)
+		case "VP8 ":
+			return nil, fmt.Errorf("%w: lossy VP8 image data", ErrUnsupportedWebP)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: missing image data", ErrInvalidWebP)
+}
+
+// DecodeWebP reads an animated WebP, compositing each frame onto the canvas
+// per its disposal and blend flags.
+//
+// Still WebPs decode as a single frame lasting DefaultImportDelay.
+// Frames must be lossless (VP8L); lossy (VP8) frames report ErrUnsupportedWebP.
+func DecodeWebP(r io.Reader) (*Animation, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, fmt.Errorf("%w: not a WebP", ErrInvalidWebP)
+	}
+
+	riffEnd := min(8+int64(binary.LittleEndian.Uint32(data[4:])), int64(len(data)))
+
+	if riffEnd < 12 {
+		return nil, fmt.Errorf("%w: RIFF size %d", ErrInvalidWebP, riffEnd-8)
+	}
+
+	chunks, err := readWebPChunks(data[12:riffEnd])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("%w: no chunks", ErrInvalidWebP)
+	}
+
+	if chunks[0].kind != "VP8X" || len(chunks[0].data) < 10 || chunks[0].data[0]&webpAnimationFlag == 0 {
+		img, err2 := decodeWebPImage(chunks)
+		if err2 != nil {
+			return nil, err2
+		}
+
+		return &Animation{Timeline: Timeline{{Image: img, Delay: DefaultImportDelay}}, LoopCount: -1}, nil
+	}
+
+	canvasWidth, canvasHeight := uint24(chunks[0].data[4:])+1, uint24(chunks[0].data[7:])+1
+
+	if canvasWidth*canvasHeight > webpMaxPixels {
+		return nil, fmt.Errorf("%w: canvas %dx%d", ErrInvalidWebP, canvasWidth, canvasHeight)
+	}
+
+	// Every frame clones the canvas.
+	var frames int64
+
+	for _, chunk := range chunks[1:] {
+		if chunk.kind == "ANMF" {
+			frames++
+		}
+	}
+
+	if pixels := int64(canvasWidth) * int64(canvasHeight) * frames; pixels > decodeBudget(int64(len(data))) {
+		return nil, fmt.Errorf("%w: %d frames of a %dx%d canvas exceed the decoding budget", ErrInvalidWebP, frames, canvasWidth, canvasHeight)
+	}
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, canvasWidth, canvasHeight))
+	var timeline Timeline
+	plays := 0
+
+	for _, chunk := range chunks[1:] {
+		switch chunk.kind {
+		case "ANIM":
+			if len(chunk.data) < 6 {
+				return nil, fmt.Errorf("%w: ANIM length %d", ErrInvalidWebP, len(chunk.data))
+			}
+
+			plays = int(binary.LittleEndian.Uint16(chunk.data[4:]))
+		case "ANMF":
+			if len(chunk.data) < 16 {
+				return nil, fmt.Errorf("%w: ANMF length %d", ErrInvalidWebP, len(chunk.data))
+			}
+
+			x, y := 2*uint24(chunk.data), 2*uint24(chunk.data[3:])
+			area := image.Rect(x, y, x+uint24(chunk.data[6:])+1, y+uint24(chunk.data[9:])+1)
+			duration, flags := uint24(chunk.data[12:]), chunk.data[15]
+			i := len(timeline)
+
+			if !area.In(canvas.Rect) {
+				return nil, fmt.Errorf("%w: frame %d exceeds the canvas", ErrInvalidWebP, i)
+			}
+
+			frameChunks, err2 := readWebPChunks(chunk.data[16:])
+			if err2 != nil {
+				return nil, fmt.Errorf("frame %d: %w", i, err2)
+			}
+
+			img, err2 := decodeWebPImage(frameChunks)
+			if err2 != nil {
+				return nil, fmt.Errorf("frame %d: %w", i, err2)
+			}
+
+			if img.Rect.Size() != area.Size() {
+				return nil, fmt.Errorf("%w: frame %d image size differs from the frame", ErrInvalidWebP, i)
+			}
+
+			blendNRGBA(canvas, area, img, flags&webpNoBlendFlag == 0)
+
+			// Durations are in milliseconds.
+			timeline = append(timeline, Frame{Image: cloneNRGBA(canvas), Delay: (duration + 5) / 10})
+
+			if flags&webpDisposeFlag != 0 {
+				draw.Draw(canvas, area, image.Transparent, image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	if len(timeline) == 0 {
+		return nil, ErrNoFrames
+	}
+
+	return &Animation{Timeline: timeline, LoopCount: loopCountForPlays(plays)}, nil
+}
+
+// EncodeWebP writes an animated WebP of lossless VP8L frames,
+// free of palette quantization.
+//
+// Each frame after the first covers just the area that changed from the previous frame.
+func EncodeWebP(w io.Writer, animation *Animation) error {
+	if len(animation.Timeline) == 0 {
+		return ErrNoFrames
+	}
+
+	bounds := animation.Bounds()
+
+	if bounds.Empty() || bounds.Dx() > vp8lMaxSize || bounds.Dy() > vp8lMaxSize {
+		return fmt.Errorf("%w: canvas %dx%d", ErrUnsupportedWebP, bounds.Dx(), bounds.Dy())
+	}
+
+	frames := make([]*image.NRGBA, len(animation.Timeline))
+	alpha := false
+
+	for i, frame := range animation.Timeline {
+		if frame.Image == nil {
+			return fmt.Errorf("frame %d: %w", i, ErrInvalidFrame)
+		}
+
+		frames[i] = toNRGBA(frame.Image, bounds)
+		alpha = alpha || !frames[i].Opaque()
+	}
+
+	areas := make([]image.Rectangle, len(frames))
+	bitstreams := make([][]byte, len(frames))
+	errs := make([]error, len(frames))
+
+	if err := forEachFrame(context.Background(), StageEncode, len(frames), func(i int) func() {
+		return func() {
+			areas[i] = bounds
+
+			if i > 0 {
+				areas[i] = changedArea(frames[i-1], frames[i])
+			}
+
+			bitstreams[i], errs[i] = encodeVP8L(cropNRGBA(frames[i], areas[i]))
+		}
+	}); err != nil {
+		return err
+	}
+
+	var body []byte
+	flags := byte(webpAnimationFlag)
+
+	if alpha {
+		flags |= webpAlphaFlag
+	}
+
+	vp8x := []byte{flags, 0, 0, 0}
+	vp8x = appendUint24(vp8x, bounds.Dx()-1)
+	vp8x = appendUint24(vp8x, bounds.Dy()-1)
+	body = appendWebPChunk(body, "VP8X", vp8x)
+
+	// Transparent background, per the canvas of DecodeWebP.
+	anim := binary.LittleEndian.AppendUint16([]byte{0, 0, 0, 0}, uint16(min(animation.Plays(), 0xFFFF)))
+	body = appendWebPChunk(body, "ANIM", anim)
+
+	for i, bitstream := range bitstreams {
+		if errs[i] != nil {
+			return fmt.Errorf("frame %d: %w", i, errs[i])
+		}
+
+		area := areas[i]
+		anmf := appendUint24(nil, (area.Min.X-bounds.Min.X)/2)
+		anmf = appendUint24(anmf, (area.Min.Y-bounds.Min.Y)/2)
+		anmf = appendUint24(anmf, area.Dx()-1)
+		anmf = appendUint24(anmf, area.Dy()-1)
+		anmf = appendUint24(anmf, min(max(animation.Timeline[i].Delay, 0)*10, 0xFFFFFF))
+
+		// Replace the area outright, keeping it for the next frame.
+		anmf = append(anmf, webpNoBlendFlag)
+		anmf = appendWebPChunk(anmf, "VP8L", bitstream)
+		body = appendWebPChunk(body, "ANMF", anmf)
+	}
+
+	header := binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(4+len(body)))
+
+	if _, err := w.Write(append(header, "WEBP"...)); err != nil {
+		return err
+	}
+
+	_, err := w.Write(body)
+	return err
+}
+
+// changedArea bounds the pixels differing between images of equal bounds,
+// aligned to even offsets as WebP frames require.
+//
+// Identical images yield a single pixel area.
+func changedArea(previous, current *image.NRGBA) image.Rectangle {
+	var area image.Rectangle
+	bounds := current.Rect
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		previousRow := previous.Pix[previous.PixOffset(bounds.Min.X, y):][:4*bounds.Dx()]
+		currentRow := current.Pix[current.PixOffset(bounds.Min.X, y):][:4*bounds.Dx()]
+
+		if bytes.Equal(previousRow, currentRow) {
+			continue
+		}
+
+		first, last := 0, bounds.Dx()-1
+
+		for ; first < last && bytes.Equal(previousRow[4*first:4*first+4], currentRow[4*first:4*first+4]); first++ {
+		}
+
+		for ; last > first && bytes.Equal(previousRow[4*last:4*last+4], currentRow[4*last:4*last+4]); last-- {
+		}
+
+		area = area.Union(image.Rect(bounds.Min.X+first, y, bounds.Min.X+last+1, y+1))
+	}
+
+	if area.Empty() {
+		return image.Rectangle{Min: bounds.Min, Max: bounds.Min.Add(image.Pt(1, 1))}
+	}
+
+	area.Min.X -= (area.Min.X - bounds.Min.X) % 2
+	area.Min.Y -= (area.Min.Y - bounds.Min.Y) % 2
+	return area
+}
+
+// cropNRGBA copies an area of an image into a new image at the origin.
+func cropNRGBA(img *image.NRGBA, area image.Rectangle) *image.NRGBA {
+	cropped := image.NewNRGBA(image.Rect(0, 0, area.Dx(), area.Dy()))
+
+	for y := 0; y < area.Dy(); y++ {
+		copy(cropped.Pix[y*cropped.Stride:(y+1)*cropped.Stride], img.Pix[img.PixOffset(area.Min.X, area.Min.Y+y):])
+	}
+
+	return cropped
+}
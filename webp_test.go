@@ -0,0 +1,122 @@
+package buttery_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+func TestWebPRoundTrip(t *testing.T) {
+	// Gradients exceed 256 colors, exercising prediction,
+	// while two colors exercise bundled color indices.
+	gradient := image.NewNRGBA(image.Rect(0, 0, 37, 21))
+	twoTone := image.NewNRGBA(gradient.Rect)
+
+	for y := 0; y < 21; y++ {
+		for x := 0; x < 37; x++ {
+			gradient.SetNRGBA(x, y, color.NRGBA{R: uint8(7 * x), G: uint8(11 * y), B: uint8(x * y), A: uint8(255 - 3*y)})
+			twoTone.SetNRGBA(x, y, color.NRGBA{R: uint8(255 * ((x + y) % 2)), A: 0xFF})
+		}
+	}
+
+	edited := image.NewNRGBA(gradient.Rect)
+	copy(edited.Pix, gradient.Pix)
+	edited.SetNRGBA(13, 9, color.NRGBA{R: 1, G: 2, B: 3, A: 4})
+	sourceAnimation := &buttery.Animation{
+		Timeline: buttery.Timeline{
+			{Image: gradient, Delay: 3},
+			{Image: edited, Delay: 5},
+			{Image: edited, Delay: 5},
+			{Image: twoTone, Delay: 8},
+		},
+		LoopCount: 0,
+	}
+
+	var buf bytes.Buffer
+
+	if err := buttery.EncodeWebP(&buf, sourceAnimation); err != nil {
+		t.Fatal(err)
+	}
+
+	animation, err := buttery.DecodeWebP(&buf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if animation.LoopCount != sourceAnimation.LoopCount {
+		t.Errorf("expected loop count %d, got %d", sourceAnimation.LoopCount, animation.LoopCount)
+	}
+
+	if delays := animation.Timeline.Delays(); !slices.Equal(delays, sourceAnimation.Timeline.Delays()) {
+		t.Errorf("expected delays %v, got %v", sourceAnimation.Timeline.Delays(), delays)
+	}
+
+	if len(animation.Timeline) != len(sourceAnimation.Timeline) {
+		t.Fatalf("expected %d frames, got %d", len(sourceAnimation.Timeline), len(animation.Timeline))
+	}
+
+	for i, frame := range sourceAnimation.Timeline {
+		expected := frame.Image.(*image.NRGBA)
+
+		for y := 0; y < 21; y++ {
+			for x := 0; x < 37; x++ {
+				c := color.NRGBAModel.Convert(animation.Timeline[i].Image.At(x, y))
+
+				if c != expected.NRGBAAt(x, y) {
+					t.Fatalf("expected frame %d pixel (%d, %d) %v, got %v", i, x, y, expected.NRGBAAt(x, y), c)
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeWebPRejectsLossy(t *testing.T) {
+	lossy := []byte("RIFF\x0c\x00\x00\x00WEBPVP8 \x00\x00\x00\x00")
+
+	if _, err := buttery.DecodeWebP(bytes.NewReader(lossy)); !errors.Is(err, buttery.ErrUnsupportedWebP) {
+		t.Errorf("expected ErrUnsupportedWebP, got %v", err)
+	}
+}
+
+func TestDecodeWebPRejectsCorruptHeaders(t *testing.T) {
+	for name, data := range map[string]string{
+		"short RIFF size":  "RIFF\x00\x00\x00\x00WEBPVP8L\x00\x00\x00\x00",
+		"oversized canvas": "RIFF\x1e\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00\x02\x00\x00\x00\xff\xff\xff\xff\xff\xff",
+	} {
+		if _, err := buttery.DecodeWebP(strings.NewReader(data)); !errors.Is(err, buttery.ErrInvalidWebP) {
+			t.Errorf("%v: expected ErrInvalidWebP, got %v", name, err)
+		}
+	}
+}
+
+func TestDecodeWebPRejectsFrameAmplification(t *testing.T) {
+	var timeline buttery.Timeline
+
+	for i := 0; i < 6; i++ {
+		img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+		img.SetNRGBA(0, 0, color.NRGBA{R: uint8(i * 40), A: 0xFF})
+		timeline = append(timeline, buttery.Frame{Image: img, Delay: 4})
+	}
+
+	var buf bytes.Buffer
+
+	if err := buttery.EncodeWebP(&buf, &buttery.Animation{Timeline: timeline}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Declare an 8192x8192 canvas, leaving the 1x1 frames in bounds.
+	amplified := buf.Bytes()
+	vp8x := bytes.Index(amplified, []byte("VP8X")) + 8
+	copy(amplified[vp8x+4:], []byte{0xFF, 0x1F, 0x00, 0xFF, 0x1F, 0x00})
+
+	if _, err := buttery.DecodeWebP(bytes.NewReader(amplified)); !errors.Is(err, buttery.ErrInvalidWebP) {
+		t.Errorf("expected ErrInvalidWebP for %d frames of an 8192x8192 canvas from %d bytes, got %v", len(timeline), len(amplified), err)
+	}
+}
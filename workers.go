@@ -0,0 +1,75 @@
+package buttery
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// workersKey indexes a worker count within a context.
+type workersKey struct{}
+
+// WithWorkers generates a context that processes independent frames across the given number of goroutines.
+//
+// Zero or negative indicates one worker per CPU.
+func WithWorkers(ctx context.Context, workers int) context.Context {
+	return context.WithValue(ctx, workersKey{}, workers)
+}
+
+// Workers queries the worker count of a context, defaulting to one worker per CPU.
+func Workers(ctx context.Context) int {
+	if workers, ok := ctx.Value(workersKey{}).(int); ok && workers > 0 {
+		return workers
+	}
+
+	return runtime.GOMAXPROCS(0)
+}
+
+// forEachFrame processes frames across Workers(ctx) goroutines,
+// reporting progress and honoring cancellation.
+//
+// prepare runs serially, in frame order, and returns a job to run concurrently.
+// Jobs must only write results specific to their frame,
+// so that output is identical regardless of worker count.
+func forEachFrame(ctx context.Context, stage Stage, frames int, prepare func(i int) func()) error {
+	var wg sync.WaitGroup
+	var progressMutex sync.Mutex
+	var done int
+
+	type indexedJob struct {
+		i   int
+		job func()
+	}
+
+	indexedJobs := make(chan indexedJob)
+
+	for w := 0; w < min(Workers(ctx), max(frames, 1)); w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for ij := range indexedJobs {
+				ij.job()
+				progressMutex.Lock()
+				done++
+				ReportProgress(ctx, Progress{Stage: stage, Frame: ij.i, Done: done, Frames: frames})
+				progressMutex.Unlock()
+			}
+		}()
+	}
+
+	var err error
+
+	for i := 0; i < frames; i++ {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+
+		indexedJobs <- indexedJob{i: i, job: prepare(i)}
+	}
+
+	close(indexedJobs)
+	wg.Wait()
+	return err
+}
@@ -0,0 +1,512 @@
+package buttery
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// y4mSignature begins YUV4MPEG2 streams.
+const y4mSignature = "YUV4MPEG2"
+
+// y4mMaxLine bounds stream and frame header lines.
+const y4mMaxLine = 4096
+
+// y4mMaxPixels bounds the frame area, guarding against corrupt headers.
+const y4mMaxPixels = 1 << 28
+
+// y4mDeepColorSpace matches color spaces with more than 8 bits per sample, such as 420p10 and mono16.
+var y4mDeepColorSpace = regexp.MustCompile(`^(420|422|444|mono)p?([0-9]+)$`)
+
+// y4mLayout models the planes of a Y4M color space.
+type y4mLayout struct {
+	// chromaShiftX and chromaShiftY denote chroma subsampling, as powers of two.
+	chromaShiftX, chromaShiftY int
+
+	// mono indicates a lone luma plane.
+	mono bool
+
+	// alpha indicates a trailing alpha plane.
+	alpha bool
+
+	// depth denotes the bits per sample. Deeper than 8 bits, samples take two little endian bytes.
+	depth int
+}
+
+// parseY4MColorSpace looks up the planes of a Y4M C parameter.
+func parseY4MColorSpace(colorSpace string) (y4mLayout, error) {
+	base, layout := colorSpace, y4mLayout{depth: 8}
+
+	if match := y4mDeepColorSpace.FindStringSubmatch(colorSpace); match != nil {
+		depth, err := strconv.Atoi(match[2])
+		if err != nil {
+			return y4mLayout{}, err
+		}
+
+		base, layout.depth = match[1], depth
+
+		if layout.depth < 8 || layout.depth > 16 {
+			return y4mLayout{}, fmt.Errorf("%w: color space %v", ErrUnsupportedY4M, colorSpace)
+		}
+	}
+
+	switch base {
+	case "420", "420jpeg", "420paldv", "420mpeg2":
+		layout.chromaShiftX, layout.chromaShiftY = 1, 1
+	case "422":
+		layout.chromaShiftX = 1
+	case "444":
+	case "444alpha":
+		layout.alpha = true
+	case "mono":
+		layout.mono = true
+	default:
+		return y4mLayout{}, fmt.Errorf("%w: color space %v", ErrUnsupportedY4M, colorSpace)
+	}
+
+	return layout, nil
+}
+
+// y4mRange models the scaling of Y'CbCr samples, per BT.601.
+type y4mRange struct {
+	yOffset, yScale, cScale float64
+}
+
+// Y4M sample ranges, per the XCOLORRANGE extension.
+var (
+	y4mFullRange    = y4mRange{yOffset: 0, yScale: 1, cScale: 1}
+	y4mLimitedRange = y4mRange{yOffset: 16, yScale: 255.0 / 219, cScale: 255.0 / 224}
+)
+
+// y4mHeader models the parameters of a Y4M stream header.
+type y4mHeader struct {
+	width, height int
+	rateNum       int64
+	rateDen       int64
+	layout        y4mLayout
+	sampleRange   y4mRange
+}
+
+// readY4MLine reads a header line, without the trailing newline.
+func readY4MLine(br *bufio.Reader) (string, error) {
+	var line []byte
+
+	for {
+		chunk, err := br.ReadSlice('\n')
+		line = append(line, chunk...)
+
+		if len(line) > y4mMaxLine {
+			return "", fmt.Errorf("%w: header line exceeds %d bytes", ErrInvalidY4M, y4mMaxLine)
+		}
+
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+
+		if err != nil {
+			if len(line) > 0 && errors.Is(err, io.EOF) {
+				err = fmt.Errorf("%w: truncated header line", ErrInvalidY4M)
+			}
+
+			return "", err
+		}
+
+		return string(line[:len(line)-1]), nil
+	}
+}
+
+// parseY4MHeader parses the parameters of a Y4M stream header.
+//
+// Streams without an XCOLORRANGE extension default to limited range, as ffmpeg writes.
+func parseY4MHeader(line string) (y4mHeader, error) {
+	fields := strings.Fields(line)
+
+	if len(fields) == 0 || fields[0] != y4mSignature {
+		return y4mHeader{}, fmt.Errorf("%w: not a Y4M", ErrInvalidY4M)
+	}
+
+	header := y4mHeader{layout: y4mLayout{chromaShiftX: 1, chromaShiftY: 1, depth: 8}, sampleRange: y4mLimitedRange}
+
+	for _, field := range fields[1:] {
+		value := field[1:]
+		var err error
+
+		switch field[0] {
+		case 'W':
+			header.width, err = strconv.Atoi(value)
+		case 'H':
+			header.height, err = strconv.Atoi(value)
+		case 'F':
+			num, den, ok := strings.Cut(value, ":")
+
+			if !ok {
+				return y4mHeader{}, fmt.Errorf("%w: frame rate %v", ErrInvalidY4M, value)
+			}
+
+			header.rateNum, err = strconv.ParseInt(num, 10, 32)
+
+			if err == nil {
+				header.rateDen, err = strconv.ParseInt(den, 10, 32)
+			}
+		case 'C':
+			header.layout, err = parseY4MColorSpace(value)
+		case 'X':
+			switch value {
+			case "COLORRANGE=FULL":
+				header.sampleRange = y4mFullRange
+			case "COLORRANGE=LIMITED":
+				header.sampleRange = y4mLimitedRange
+			}
+		}
+
+		if err != nil {
+			if errors.Is(err, ErrUnsupportedY4M) {
+				return y4mHeader{}, err
+			}
+
+			return y4mHeader{}, fmt.Errorf("%w: header parameter %v", ErrInvalidY4M, field)
+		}
+	}
+
+	if header.width <= 0 || header.height <= 0 || int64(header.width)*int64(header.height) > y4mMaxPixels {
+		return y4mHeader{}, fmt.Errorf("%w: dimensions %dx%d", ErrInvalidY4M, header.width, header.height)
+	}
+
+	if header.rateNum <= 0 || header.rateDen <= 0 {
+		return y4mHeader{}, fmt.Errorf("%w: frame rate %d:%d", ErrInvalidY4M, header.rateNum, header.rateDen)
+	}
+
+	return header, nil
+}
+
+// boundary converts a frame index to the nearest centisec
+// at which that frame begins, per the frame rate.
+func (o y4mHeader) boundary(i int) int {
+	return int((200*int64(i)*o.rateDen + o.rateNum) / (2 * o.rateNum))
+}
+
+// Y4MOptions configures YUV4MPEG2 encoding and decoding.
+type Y4MOptions struct {
+	// Alpha encodes 4:4:4 chroma with an alpha plane (C444alpha),
+	// rather than opaque 4:2:0 chroma (C420jpeg), which few video tools accept.
+	Alpha bool
+
+	// MergeRepeats decodes identical consecutive frames as one longer frame,
+	// such as to recover the delays of streams repeating frames to fit a frame rate.
+	MergeRepeats bool
+}
+
+// DecodeY4M reads a YUV4MPEG2 stream with default options, see Y4MOptions.Decode.
+func DecodeY4M(r io.Reader) (*Animation, error) {
+	return Y4MOptions{}.Decode(r)
+}
+
+// Decode reads a YUV4MPEG2 stream, such as from ffmpeg -f yuv4mpegpipe,
+// converting BT.601 Y'CbCr frames to RGB.
+//
+// Frame delays follow the frame rate, rounded such that each frame begins at the nearest centisec.
+// The animation loops infinitely.
+func (o Y4MOptions) Decode(r io.Reader) (*Animation, error) {
+	br := bufio.NewReader(r)
+	line, err := readY4MLine(br)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: not a Y4M", ErrInvalidY4M)
+		}
+
+		return nil, err
+	}
+
+	header, err := parseY4MHeader(line)
+	if err != nil {
+		return nil, err
+	}
+
+	planes := newY4MPlanes(header)
+	var timeline Timeline
+
+	for i := 0; ; i++ {
+		frameLine, err2 := readY4MLine(br)
+
+		if errors.Is(err2, io.EOF) {
+			break
+		}
+
+		if err2 != nil {
+			return nil, err2
+		}
+
+		if !strings.HasPrefix(frameLine, "FRAME") {
+			return nil, fmt.Errorf("%w: frame %d header %q", ErrInvalidY4M, i, frameLine)
+		}
+
+		if _, err3 := io.ReadFull(br, planes.data); err3 != nil {
+			return nil, fmt.Errorf("%w: frame %d truncated", ErrInvalidY4M, i)
+		}
+
+		img := planes.nrgba(header.sampleRange)
+		delay := header.boundary(i+1) - header.boundary(i)
+
+		if o.MergeRepeats && len(timeline) > 0 && bytes.Equal(timeline[len(timeline)-1].Image.(*image.NRGBA).Pix, img.Pix) {
+			timeline[len(timeline)-1].Delay += delay
+			continue
+		}
+
+		timeline = append(timeline, Frame{Image: img, Delay: delay})
+	}
+
+	if len(timeline) == 0 {
+		return nil, ErrNoFrames
+	}
+
+	return &Animation{Timeline: timeline, LoopCount: 0}, nil
+}
+
+// y4mPlanes models the sample planes of a Y4M frame.
+type y4mPlanes struct {
+	layout             y4mLayout
+	width, height      int
+	chromaWidth        int
+	chromaHeight       int
+	bytesPerSample     int
+	data               []byte
+	cbOffset, crOffset int
+	alphaOffset        int
+	depthScale         float64
+}
+
+// newY4MPlanes allocates the frame planes of a Y4M stream.
+func newY4MPlanes(header y4mHeader) *y4mPlanes {
+	o := &y4mPlanes{
+		layout:         header.layout,
+		width:          header.width,
+		height:         header.height,
+		chromaWidth:    (header.width + 1<<header.layout.chromaShiftX - 1) >> header.layout.chromaShiftX,
+		chromaHeight:   (header.height + 1<<header.layout.chromaShiftY - 1) >> header.layout.chromaShiftY,
+		bytesPerSample: 1,
+		depthScale:     float64(int(1) << (header.layout.depth - 8)),
+	}
+
+	if header.layout.depth > 8 {
+		o.bytesPerSample = 2
+	}
+
+	lumaSize := o.width * o.height * o.bytesPerSample
+	chromaSize := o.chromaWidth * o.chromaHeight * o.bytesPerSample
+
+	if o.layout.mono {
+		chromaSize = 0
+	}
+
+	o.cbOffset = lumaSize
+	o.crOffset = o.cbOffset + chromaSize
+	o.alphaOffset = o.crOffset + chromaSize
+	size := o.alphaOffset
+
+	if o.layout.alpha {
+		size += lumaSize
+	}
+
+	o.data = make([]byte, size)
+	return o
+}
+
+// sample reads a plane sample, scaled to 8 bits,
+// as deeper samples shift 8-bit levels left.
+func (o *y4mPlanes) sample(offset, i int) float64 {
+	if o.bytesPerSample == 1 {
+		return float64(o.data[offset+i])
+	}
+
+	v := int(o.data[offset+2*i]) | int(o.data[offset+2*i+1])<<8
+	return float64(v) / o.depthScale
+}
+
+// nrgba converts the planes to RGB.
+func (o *y4mPlanes) nrgba(sampleRange y4mRange) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, o.width, o.height))
+
+	for y := 0; y < o.height; y++ {
+		for x := 0; x < o.width; x++ {
+			luma := (o.sample(0, y*o.width+x) - sampleRange.yOffset) * sampleRange.yScale
+			var cb, cr float64
+
+			if !o.layout.mono {
+				chroma := (y>>o.layout.chromaShiftY)*o.chromaWidth + x>>o.layout.chromaShiftX
+				cb = (o.sample(o.cbOffset, chroma) - 128) * sampleRange.cScale
+				cr = (o.sample(o.crOffset, chroma) - 128) * sampleRange.cScale
+			}
+
+			p := img.Pix[img.PixOffset(x, y):]
+			p[0] = clampSample(luma + 1.402*cr)
+			p[1] = clampSample(luma - 0.344136*cb - 0.714136*cr)
+			p[2] = clampSample(luma + 1.772*cb)
+			p[3] = 0xFF
+
+			if o.layout.alpha {
+				p[3] = clampSample(o.sample(o.alphaOffset, y*o.width+x))
+			}
+		}
+	}
+
+	return img
+}
+
+// clampSample rounds a sample to 8 bits.
+func clampSample(v float64) uint8 {
+	return uint8(min(max(math.Round(v), 0), 255))
+}
+
+// EncodeY4M writes a YUV4MPEG2 stream with default options, see Y4MOptions.Encode.
+func EncodeY4M(w io.Writer, animation *Animation) error {
+	return Y4MOptions{}.Encode(w, animation)
+}
+
+// Encode writes a full range YUV4MPEG2 stream, such as for ffmpeg -f yuv4mpegpipe,
+// converting RGB frames to BT.601 Y'CbCr.
+//
+// Opaque 4:2:0 output composites translucent pixels over black.
+//
+// The frame rate is the coarsest that times every delay exactly,
+// repeating longer frames as needed. 0cs frames drop out.
+func (o Y4MOptions) Encode(w io.Writer, animation *Animation) error {
+	if len(animation.Timeline) == 0 {
+		return ErrNoFrames
+	}
+
+	bounds := animation.Bounds()
+
+	if bounds.Empty() {
+		return ErrInvalidFrame
+	}
+
+	frames := make([]*image.NRGBA, len(animation.Timeline))
+	delays := make([]int, len(animation.Timeline))
+	tick := 0
+
+	for i, frame := range animation.Timeline {
+		if frame.Image == nil {
+			return fmt.Errorf("frame %d: %w", i, ErrInvalidFrame)
+		}
+
+		frames[i] = toNRGBA(frame.Image, bounds)
+		delays[i] = max(frame.Delay, 0)
+		tick = gcd(tick, delays[i])
+	}
+
+	// Without any timing, each frame plays once.
+	if tick == 0 {
+		tick = DefaultImportDelay
+
+		for i := range delays {
+			delays[i] = tick
+		}
+	}
+
+	colorSpace := "420jpeg"
+
+	if o.Alpha {
+		colorSpace = "444alpha"
+	}
+
+	divisor := gcd(100, tick)
+	bw := bufio.NewWriter(w)
+
+	if _, err := fmt.Fprintf(bw, "%v W%d H%d F%d:%d Ip A1:1 C%v XCOLORRANGE=FULL\n", y4mSignature, bounds.Dx(), bounds.Dy(), 100/divisor, tick/divisor, colorSpace); err != nil {
+		return err
+	}
+
+	for i, frame := range frames {
+		var planes []byte
+
+		if o.Alpha {
+			planes = y4mAlphaPlanesOf(frame)
+		} else {
+			planes = y4mPlanesOf(frame)
+		}
+
+		for range delays[i] / tick {
+			if _, err := bw.WriteString("FRAME\n"); err != nil {
+				return err
+			}
+
+			if _, err := bw.Write(planes); err != nil {
+				return err
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// y4mYCbCr converts an RGB color to full range Y'CbCr.
+func y4mYCbCr(r, g, b float64) (float64, float64, float64) {
+	return 0.299*r + 0.587*g + 0.114*b, 128 - 0.168736*r - 0.331264*g + 0.5*b, 128 + 0.5*r - 0.418688*g - 0.081312*b
+}
+
+// y4mPlanesOf converts an image to full range 4:2:0 Y'CbCr planes, compositing over black,
+// with each chroma sample averaging a 2x2 block of pixels.
+func y4mPlanesOf(img *image.NRGBA) []byte {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	chromaWidth, chromaHeight := (width+1)/2, (height+1)/2
+	chromaArea := chromaWidth * chromaHeight
+	cbSums, crSums, counts := make([]float64, chromaArea), make([]float64, chromaArea), make([]float64, chromaArea)
+	planes := make([]byte, width*height+2*chromaArea)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			p := img.Pix[img.PixOffset(img.Rect.Min.X+x, img.Rect.Min.Y+y):]
+			a := float64(p[3]) / 255
+			luma, cb, cr := y4mYCbCr(a*float64(p[0]), a*float64(p[1]), a*float64(p[2]))
+			planes[y*width+x] = clampSample(luma)
+			chroma := y/2*chromaWidth + x/2
+			cbSums[chroma] += cb
+			crSums[chroma] += cr
+			counts[chroma]++
+		}
+	}
+
+	for i, count := range counts {
+		planes[width*height+i] = clampSample(cbSums[i] / count)
+		planes[width*height+chromaArea+i] = clampSample(crSums[i] / count)
+	}
+
+	return planes
+}
+
+// y4mAlphaPlanesOf converts an image to full range 4:4:4 Y'CbCr planes, followed by an alpha plane.
+func y4mAlphaPlanesOf(img *image.NRGBA) []byte {
+	area := img.Rect.Dx() * img.Rect.Dy()
+	planes := make([]byte, 4*area)
+	i := 0
+
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			p := img.Pix[img.PixOffset(x, y):]
+			luma, cb, cr := y4mYCbCr(float64(p[0]), float64(p[1]), float64(p[2]))
+			planes[i] = clampSample(luma)
+			planes[area+i] = clampSample(cb)
+			planes[2*area+i] = clampSample(cr)
+			planes[3*area+i] = p[3]
+			i++
+		}
+	}
+
+	return planes
+}
+
+// gcd queries the greatest common divisor, with gcd(0, n) = n.
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
@@ -0,0 +1,149 @@
+package buttery_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mcandre/buttery"
+)
+
+func TestY4MRoundTrip(t *testing.T) {
+	var timeline buttery.Timeline
+
+	// Gray gradients over a color per frame, such that chroma is uniform within each frame.
+	for i, delay := range []int{4, 6, 10} {
+		img := image.NewNRGBA(image.Rect(0, 0, 5, 3))
+
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 5; x++ {
+				gray := uint8(10*x + 10*y)
+				img.SetNRGBA(x, y, color.NRGBA{R: uint8(90*i) + gray, G: 80 + gray, B: uint8(150-50*i) + gray, A: uint8(255 - 60*i)})
+			}
+		}
+
+		timeline = append(timeline, buttery.Frame{Image: img, Delay: delay})
+	}
+
+	sourceAnimation := &buttery.Animation{Timeline: timeline}
+
+	for _, tc := range []struct {
+		options buttery.Y4MOptions
+		header  string
+	}{
+		{options: buttery.Y4MOptions{MergeRepeats: true}, header: "YUV4MPEG2 W5 H3 F50:1 Ip A1:1 C420jpeg XCOLORRANGE=FULL"},
+		{options: buttery.Y4MOptions{Alpha: true, MergeRepeats: true}, header: "YUV4MPEG2 W5 H3 F50:1 Ip A1:1 C444alpha XCOLORRANGE=FULL"},
+	} {
+		var buf bytes.Buffer
+
+		if err := tc.options.Encode(&buf, sourceAnimation); err != nil {
+			t.Fatal(err)
+		}
+
+		if header, _, _ := strings.Cut(buf.String(), "\n"); header != tc.header {
+			t.Errorf("expected %+v header %q, got %q", tc.options, tc.header, header)
+		}
+
+		animation, err := tc.options.Decode(&buf)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if delays := animation.Timeline.Delays(); !slices.Equal(delays, sourceAnimation.Timeline.Delays()) {
+			t.Fatalf("expected %+v delays %v, got %v", tc.options, sourceAnimation.Timeline.Delays(), delays)
+		}
+
+		for i, frame := range timeline {
+			expected, actual := frame.Image.(*image.NRGBA), animation.Timeline[i].Image.(*image.NRGBA)
+
+			for j := 0; j < len(expected.Pix); j += 4 {
+				want := slices.Clone(expected.Pix[j : j+4])
+
+				// Opaque output composites over black.
+				if !tc.options.Alpha {
+					for k := range 3 {
+						want[k] = uint8((int(want[k])*int(want[3]) + 127) / 255)
+					}
+
+					want[3] = 0xFF
+				}
+
+				for k, sample := range want {
+					if d := int(sample) - int(actual.Pix[j+k]); d < -2 || d > 2 {
+						t.Fatalf("expected %+v frame %d sample %d near %d, got %d", tc.options, i, j+k, sample, actual.Pix[j+k])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestY4MRepeatsFrames(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	sourceAnimation := &buttery.Animation{Timeline: buttery.Timeline{{Image: img, Delay: 4}, {Image: img, Delay: 6}}}
+	var buf bytes.Buffer
+
+	if err := buttery.EncodeY4M(&buf, sourceAnimation); err != nil {
+		t.Fatal(err)
+	}
+
+	encoded := buf.Bytes()
+	animation, err := buttery.DecodeY4M(bytes.NewReader(encoded))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if delays := animation.Timeline.Delays(); !slices.Equal(delays, []int{2, 2, 2, 2, 2}) {
+		t.Errorf("expected repeated frames kept apart by default, got delays %v", delays)
+	}
+
+	animation, err = buttery.Y4MOptions{MergeRepeats: true}.Decode(bytes.NewReader(encoded))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if delays := animation.Timeline.Delays(); !slices.Equal(delays, []int{10}) {
+		t.Errorf("expected MergeRepeats to merge identical frames, got delays %v", delays)
+	}
+}
+
+func TestDecodeY4MFractionalRate(t *testing.T) {
+	// 4:2:0 limited range frames at 29.97 fps, each a distinct gray.
+	var buf bytes.Buffer
+	buf.WriteString("YUV4MPEG2 W2 H2 F30000:1001 Ip A1:1 C420jpeg\n")
+
+	for i := 0; i < 10; i++ {
+		buf.WriteString("FRAME\n")
+		buf.Write([]byte{byte(16 + 20*i), byte(16 + 20*i), byte(16 + 20*i), byte(16 + 20*i), 128, 128})
+	}
+
+	animation, err := buttery.DecodeY4M(&buf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each frame begins at the nearest centisec, such that 10 frames last 33cs.
+	if delays := animation.Timeline.Delays(); !slices.Equal(delays, []int{3, 4, 3, 3, 4, 3, 3, 4, 3, 3}) {
+		t.Errorf("expected delays to track 29.97 fps, got %v", delays)
+	}
+
+	if c := animation.Timeline[0].Image.At(1, 1); c != (color.NRGBA{A: 0xFF}) {
+		t.Errorf("expected limited range black, got %v", c)
+	}
+
+	if _, err := buttery.DecodeY4M(strings.NewReader("YUV4MPEG2 W2 H2 F25:1 C411\n")); !errors.Is(err, buttery.ErrUnsupportedY4M) {
+		t.Errorf("expected ErrUnsupportedY4M for 4:1:1, got %v", err)
+	}
+
+	if _, err := buttery.DecodeY4M(strings.NewReader("YUV4MPEG2 W2 H2 F25:1 C420p99999999999999999999\n")); !errors.Is(err, buttery.ErrInvalidY4M) {
+		t.Errorf("expected ErrInvalidY4M for an overflowing bit depth, got %v", err)
+	}
+}